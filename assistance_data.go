@@ -13,40 +13,37 @@ package GoFritzBox
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"mime/multipart"
-
-	"github.com/valyala/fasthttp"
 )
 
 // GetAssistanceData returns the firmwarecfg file useful to generate HLog/QLN graphs
 func (s *SessionInfo) GetAssistanceData() ([]byte, error) {
-	payload := &bytes.Buffer{}
-	writer := multipart.NewWriter(payload)
-	_ = writer.WriteField("sid", s.SID)
-	_ = writer.WriteField("SupportData", "")
-	err := writer.Close()
-	if err != nil {
-		return []byte{}, err
-	}
-
-	request := fasthttp.AcquireRequest()
-	response := fasthttp.AcquireResponse()
-	defer func() {
-		fasthttp.ReleaseRequest(request)
-		fasthttp.ReleaseResponse(response)
-	}()
-
-	request.SetRequestURI(fmt.Sprintf("%s/cgi-bin/firmwarecfg", s.EndPoint))
-	request.Header.SetContentType(writer.FormDataContentType())
-	request.Header.SetMethod(fasthttp.MethodPost)
-
-	request.SetBodyRaw(payload.Bytes())
+	return s.GetAssistanceDataContext(context.Background())
+}
 
-	err = client.Do(request, response)
+// GetAssistanceDataContext is like GetAssistanceData, ctx bounds the request and cancels it when done
+func (s *SessionInfo) GetAssistanceDataContext(ctx context.Context) ([]byte, error) {
+	response, err := s.do(ctx, readOnly, func(sid string) (*Request, error) {
+		payload := &bytes.Buffer{}
+		writer := multipart.NewWriter(payload)
+		_ = writer.WriteField("sid", sid)
+		_ = writer.WriteField("SupportData", "")
+		err := writer.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		return newPostRequest(
+			fmt.Sprintf("%s/cgi-bin/firmwarecfg", s.EndPoint),
+			writer.FormDataContentType(),
+			payload.Bytes(),
+		), nil
+	})
 	if err != nil {
 		return []byte{}, err
 	}
 
-	return response.Body(), nil
+	return response.Body, nil
 }
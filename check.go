@@ -0,0 +1,52 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * check.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"context"
+	"fmt"
+)
+
+// Check asks the Fritz!Box if the SID of the session is still accepted
+func (s *SessionInfo) Check() (SessionStatus, error) {
+	return s.CheckContext(context.Background())
+}
+
+// CheckContext is like Check, ctx bounds the request and cancels it when done
+func (s *SessionInfo) CheckContext(ctx context.Context) (SessionStatus, error) {
+	return s.checkSID(ctx, s.sid())
+}
+
+// checkSID asks the Fritz!Box if sid is still accepted
+func (s *SessionInfo) checkSID(ctx context.Context, sid string) (SessionStatus, error) {
+	if sid == "" || sid == invalidSID {
+		return SessionExpired, nil
+	}
+
+	result, err := s.client().getSessionInfo(ctx, readOnly, fmt.Sprintf("%s/login_sid.lua?version=2&sid=%s", s.EndPoint, sid))
+	if err != nil {
+		return SessionUnknown, err
+	}
+
+	if result.SID == sid {
+		return SessionValid, nil
+	} else {
+		return SessionExpired, nil
+	}
+}
+
+// Valid returns true if the Fritz!Box still accepts the SID of the session
+// Errors are reported as an invalid session, use Check to tell them apart
+func (s *SessionInfo) Valid() bool {
+	status, err := s.Check()
+	return err == nil && status == SessionValid
+}
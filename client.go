@@ -0,0 +1,96 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * client.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"context"
+	"crypto/tls"
+	"sync"
+	"time"
+)
+
+// DefaultClient is the Client used by Login and by the sessions without a Client
+var DefaultClient = &Client{}
+
+// Client contains the HTTP configuration used to talk with a Fritz!Box
+// The zero value is ready to use, fields must not be changed after the first request
+// ReadTimeout and WriteTimeout limit the time to read a response and write a request, zero means no limit
+// MaxConns is the maximum number of connections to each Fritz!Box
+// TLSConfig is used for https:// endpoints, for example to accept the self-signed certificate of the Fritz!Box
+// Proxy is an HTTP proxy in the format [user:password@]host:port, empty to connect directly
+// UserAgent is sent with every request, the Transport default is used when empty
+// Retry is the policy used to retry the requests that failed for transient errors, nil to never retry
+// Trace is called after every request with its TraceEvent, useful to debug the Fritz!Box endpoints
+// Transport sends the requests, when it's nil a FastHTTPTransport is created from the fields above,
+// otherwise only UserAgent is used and the Transport must be configured on its own
+type Client struct {
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	MaxConns     int
+	TLSConfig    *tls.Config
+	Proxy        string
+	UserAgent    string
+	Retry        *RetryPolicy
+	Trace        func(event TraceEvent)
+	Transport    Transport
+
+	once      sync.Once
+	transport Transport
+}
+
+// do sends the request with the Transport of the Client
+// Transient failures are retried following the Retry policy, when kind allows it
+func (c *Client) do(ctx context.Context, kind callKind, request *Request) (*Response, error) {
+	c.once.Do(func() {
+		c.transport = c.Transport
+		if c.transport == nil {
+			c.transport = &FastHTTPTransport{Client: newFastHTTPClient(c)}
+		}
+	})
+
+	if c.UserAgent != "" {
+		request.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	for attempt := 0; ; attempt++ {
+		release, err := acquireLimit(ctx, request.URL)
+		if err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		var response *Response
+		if transport, ok := c.transport.(backgroundTransport); ok {
+			response, err = transport.do(ctx, request, release)
+		} else {
+			response, err = c.transport.Do(ctx, request)
+			release()
+		}
+		if response != nil {
+			response.endpoint = endpointName(request.URL)
+		}
+		if c.Trace != nil {
+			c.Trace(newTraceEvent(request, response, err, attempt, time.Since(start)))
+		}
+
+		if !c.Retry.allows(kind, attempt) || !isTransient(response, err) {
+			return response, err
+		}
+
+		timer := time.NewTimer(c.Retry.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
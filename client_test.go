@@ -0,0 +1,99 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * client_test.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClientUserAgent(t *testing.T) {
+	box := newFakeBox(t)
+	client := &Client{UserAgent: "GoFritzBox test"}
+
+	session, err := client.Login(box.server.URL, "admin", fakePassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.Client != client {
+		t.Error("the session doesn't use the Client of the Login")
+	}
+
+	box.mutex.Lock()
+	defer box.mutex.Unlock()
+	if box.userAgent != client.UserAgent {
+		t.Errorf("User-Agent = %q, want %q", box.userAgent, client.UserAgent)
+	}
+}
+
+func TestClientTLSConfig(t *testing.T) {
+	box := &fakeBox{challenge: fakeChallenge}
+	server := httptest.NewTLSServer(http.HandlerFunc(box.handle))
+	defer server.Close()
+
+	if _, err := (&Client{}).Login(server.URL, "admin", fakePassword); err == nil {
+		t.Error("Login accepted a self-signed certificate without TLSConfig")
+	}
+
+	client := &Client{TLSConfig: &tls.Config{InsecureSkipVerify: true}}
+	if _, err := client.Login(server.URL, "admin", fakePassword); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestClientReadTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	client := &Client{ReadTimeout: 20 * time.Millisecond}
+	start := time.Now()
+	if _, err := client.Login(server.URL, "admin", fakePassword); err == nil {
+		t.Error("Login succeeded, want a timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("Login took %v, want less than the answer delay", elapsed)
+	}
+}
+
+func TestContextDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+	}))
+	defer server.Close()
+	session := &SessionInfo{SID: "0123456789abcdef", EndPoint: server.URL}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := session.GetLogsContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Errorf("GetLogsContext took %v, want it to stop at the deadline", elapsed)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	if _, err := LoginContext(ctx, server.URL, "admin", fakePassword); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,166 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * credentials.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Credentials returns the username and password used to login on the Fritz!Box
+// It's called on every login, so it can fetch fresh credentials from elsewhere
+type Credentials func() (username, password string, err error)
+
+// StaticCredentials returns Credentials that always return the given username and password
+func StaticCredentials(username, password string) Credentials {
+	return func() (string, string, error) {
+		return username, password, nil
+	}
+}
+
+// EnvCredentials returns Credentials read from the environment variables usernameVar and passwordVar
+// The username can be missing, to login with the last user
+func EnvCredentials(usernameVar, passwordVar string) Credentials {
+	return func() (string, string, error) {
+		password, ok := os.LookupEnv(passwordVar)
+		if !ok {
+			return "", "", fmt.Errorf("environment variable %s is not set", passwordVar)
+		}
+		return os.Getenv(usernameVar), password, nil
+	}
+}
+
+// NetrcCredentials returns Credentials read from the .netrc file at path, using the machine entry of host
+// host can be an endpoint as well, like http://fritz.box, only its host name is used
+// When path is empty, $NETRC or ~/.netrc is used. The default entry is used if there's no entry for host
+func NetrcCredentials(path, host string) Credentials {
+	return func() (string, string, error) {
+		// Resolved on every call, the Credentials can be shared and $NETRC can change
+		file := path
+		if file == "" {
+			file = os.Getenv("NETRC")
+		}
+		if file == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", "", err
+			}
+			file = filepath.Join(home, ".netrc")
+		}
+
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", "", err
+		}
+		return parseNetrc(string(data), hostName(host))
+	}
+}
+
+// parseNetrc returns login and password of the machine entry of host, or of the default entry
+func parseNetrc(data, host string) (string, string, error) {
+	var tokens []string
+	inMacro := false
+	for _, line := range strings.Split(data, "\n") {
+		// Macro definitions go on until an empty line
+		if inMacro {
+			inMacro = strings.TrimSpace(line) != ""
+			continue
+		}
+		fields := strings.Fields(line)
+		for i, field := range fields {
+			if field == "macdef" {
+				fields = fields[:i]
+				inMacro = true
+				break
+			}
+		}
+		tokens = append(tokens, fields...)
+	}
+
+	type entry struct {
+		login, password string
+	}
+	var found, fallback *entry
+	var current *entry
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "machine":
+			current = &entry{}
+			if i+1 < len(tokens) && tokens[i+1] == host && found == nil {
+				found = current
+			}
+			i++
+		case "default":
+			current = &entry{}
+			if fallback == nil {
+				fallback = current
+			}
+		case "login":
+			if i+1 < len(tokens) && current != nil {
+				current.login = tokens[i+1]
+			}
+			i++
+		case "password":
+			if i+1 < len(tokens) && current != nil {
+				current.password = tokens[i+1]
+			}
+			i++
+		case "account":
+			i++
+		}
+	}
+
+	if found == nil {
+		found = fallback
+	}
+	if found == nil {
+		return "", "", fmt.Errorf("no netrc entry for %s", host)
+	}
+	return found.login, found.password, nil
+}
+
+// FileCredentials returns Credentials read from a JSON or YAML secrets file at path, using the entry of host
+// host can be an endpoint as well, like http://fritz.box, only its host name is used
+// The file maps every host to its credentials:
+//
+//	fritz.box:
+//	  username: admin
+//	  password: secret
+func FileCredentials(path, host string) Credentials {
+	return func() (string, string, error) {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", "", err
+		}
+
+		// YAML is a superset of JSON, a single decoder handles both formats
+		var secrets map[string]struct {
+			Username string `yaml:"username"`
+			Password string `yaml:"password"`
+		}
+		err = yaml.Unmarshal(data, &secrets)
+		if err != nil {
+			return "", "", err
+		}
+
+		secret, ok := secrets[hostName(host)]
+		if !ok {
+			return "", "", fmt.Errorf("no credentials for %s in %s", hostName(host), path)
+		}
+		return secret.Username, secret.Password, nil
+	}
+}
@@ -0,0 +1,133 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * credentials_test.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestParseNetrc(t *testing.T) {
+	const netrc = `machine example.com login other password secret
+macdef init
+machine fritz.box login fake password fake
+
+machine fritz.box
+	login admin
+	password "pass"
+default login guest password guest
+`
+	tests := []struct {
+		host, username, password string
+	}{
+		{"fritz.box", "admin", `"pass"`},
+		{"example.com", "other", "secret"},
+		{"192.168.178.1", "guest", "guest"},
+	}
+	for _, test := range tests {
+		username, password, err := parseNetrc(netrc, test.host)
+		if err != nil || username != test.username || password != test.password {
+			t.Errorf("parseNetrc(%s) = %q, %q, %v, want %q, %q", test.host, username, password, err, test.username, test.password)
+		}
+	}
+
+	if _, _, err := parseNetrc("machine fritz.box login admin password pass", "other.box"); err == nil {
+		t.Error("parseNetrc succeeded for a missing host, want an error")
+	}
+}
+
+func TestEnvCredentials(t *testing.T) {
+	const usernameVar, passwordVar = "GOFRITZBOX_TEST_USERNAME", "GOFRITZBOX_TEST_PASSWORD"
+	defer os.Unsetenv(usernameVar)
+	defer os.Unsetenv(passwordVar)
+
+	credentials := EnvCredentials(usernameVar, passwordVar)
+	if _, _, err := credentials(); err == nil {
+		t.Error("EnvCredentials succeeded without the password variable")
+	}
+
+	os.Setenv(usernameVar, "admin")
+	os.Setenv(passwordVar, "secret")
+	username, password, err := credentials()
+	if err != nil || username != "admin" || password != "secret" {
+		t.Errorf("EnvCredentials() = %q, %q, %v, want admin, secret", username, password, err)
+	}
+}
+
+func TestNetrcCredentials(t *testing.T) {
+	path := writeTestFile(t, ".netrc", "machine fritz.box login admin password secret\n")
+
+	username, password, err := NetrcCredentials(path, "http://fritz.box:8080")()
+	if err != nil || username != "admin" || password != "secret" {
+		t.Errorf("NetrcCredentials() = %q, %q, %v, want admin, secret", username, password, err)
+	}
+}
+
+func TestNetrcCredentialsFromEnv(t *testing.T) {
+	old, ok := os.LookupEnv("NETRC")
+	defer func() {
+		if ok {
+			os.Setenv("NETRC", old)
+		} else {
+			os.Unsetenv("NETRC")
+		}
+	}()
+
+	credentials := NetrcCredentials("", "fritz.box")
+	var wg sync.WaitGroup
+	for _, login := range []string{"first", "second"} {
+		os.Setenv("NETRC", writeTestFile(t, ".netrc", "machine fritz.box login "+login+" password secret\n"))
+
+		// Credentials are shared by the goroutines of a session
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func(login string) {
+				defer wg.Done()
+				username, _, err := credentials()
+				if err != nil || username != login {
+					t.Errorf("NetrcCredentials() = %q, %v, want %s from $NETRC", username, err, login)
+				}
+			}(login)
+		}
+		wg.Wait()
+	}
+}
+
+func TestFileCredentials(t *testing.T) {
+	files := map[string]string{
+		"secrets.json": `{"fritz.box": {"username": "admin", "password": "secret"}}`,
+		"secrets.yaml": "fritz.box:\n  username: admin\n  password: secret\n",
+	}
+	for name, content := range files {
+		path := writeTestFile(t, name, content)
+
+		username, password, err := FileCredentials(path, "https://fritz.box")()
+		if err != nil || username != "admin" || password != "secret" {
+			t.Errorf("%s: FileCredentials() = %q, %q, %v, want admin, secret", name, username, password, err)
+		}
+		if _, _, err := FileCredentials(path, "other.box")(); err == nil {
+			t.Errorf("%s: FileCredentials succeeded for a missing host", name)
+		}
+	}
+}
+
+// writeTestFile writes content to a file called name in a temporary directory
+func writeTestFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -12,37 +12,33 @@
 package GoFritzBox
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"time"
-
-	"github.com/valyala/fasthttp"
 )
 
 // Disconnect disconnects your Fritz!Box from the internet
 // This is usually used to change your IP address
 // The prodecure can require up to 30 seconds, after that the internet connection will be re-enabled
 func (s *SessionInfo) Disconnect() error {
-	request := fasthttp.AcquireRequest()
-	response := fasthttp.AcquireResponse()
-	defer func() {
-		fasthttp.ReleaseRequest(request)
-		fasthttp.ReleaseResponse(response)
-	}()
-
-	request.SetRequestURI(fmt.Sprintf(
-		"%s/internet/inetstat_monitor.lua?sid=%s&myXhr=1&action=disconnect&useajax=1&xhr=1&t%d=nocache",
-		s.EndPoint, s.SID, time.Now().Unix(),
-	))
+	return s.DisconnectContext(context.Background())
+}
 
-	err := client.Do(request, response)
+// DisconnectContext is like Disconnect, ctx bounds the request and cancels it when done
+func (s *SessionInfo) DisconnectContext(ctx context.Context) error {
+	response, err := s.do(ctx, changesState, func(sid string) (*Request, error) {
+		return newGetRequest(fmt.Sprintf(
+			"%s/internet/inetstat_monitor.lua?sid=%s&myXhr=1&action=disconnect&useajax=1&xhr=1&t%d=nocache",
+			s.EndPoint, sid, time.Now().Unix(),
+		)), nil
+	})
 	if err != nil {
 		return err
 	}
 
-	if string(response.Body()) == "done:0" {
+	if string(response.Body) == "done:0" {
 		return nil
 	} else {
-		return errors.New("failed to disconnect")
+		return &ProtocolError{Endpoint: response.endpoint, Err: fmt.Errorf("failed to disconnect, answer %q", response.Body)}
 	}
 }
@@ -0,0 +1,101 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * endpoint.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// NormalizeEndpoint returns the endpoint in the form scheme://host[:port][/prefix], the one stored in EndPoint
+// It accepts host names and IP addresses without scheme, like fritz.box, 192.168.178.1 or fe80::1, which use http,
+// except for MyFRITZ! addresses (*.myfritz.net), which are reachable only with https
+// The default port and the trailing slash are removed, a path is kept as prefix of the Fritz!Box pages,
+// for remote access through a reverse proxy like https://example.com/fritzbox
+// An EndpointError is returned for anything else
+func NormalizeEndpoint(endpoint string) (string, error) {
+	raw := strings.TrimSpace(endpoint)
+	if raw == "" {
+		return "", &EndpointError{Endpoint: endpoint, Reason: "empty endpoint"}
+	}
+
+	if ip := net.ParseIP(raw); ip != nil && strings.Contains(raw, ":") {
+		// A bare IPv6 address, the port can't be written without brackets
+		raw = "[" + raw + "]"
+	}
+
+	// A scheme with a single slash, like http:/fritz.box, is parsed as it is and has no host
+	if !schemePrefix.MatchString(raw) {
+		host := strings.ToLower(strings.SplitN(strings.SplitN(raw, "/", 2)[0], ":", 2)[0])
+		if strings.HasSuffix(host, ".myfritz.net") {
+			raw = "https://" + raw
+		} else {
+			raw = "http://" + raw
+		}
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", &EndpointError{Endpoint: endpoint, Reason: err.Error()}
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", &EndpointError{Endpoint: endpoint, Reason: "scheme must be http or https"}
+	}
+	if u.Hostname() == "" {
+		return "", &EndpointError{Endpoint: endpoint, Reason: "missing host"}
+	}
+	if u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return "", &EndpointError{Endpoint: endpoint, Reason: "credentials, query and fragment are not allowed"}
+	}
+	if !strings.HasPrefix(u.Host, "[") && strings.Count(u.Host, ":") > 1 {
+		// url.Parse reads http://fe80::1 as the host fe80: with the port 1
+		return "", &EndpointError{Endpoint: endpoint, Reason: "IPv6 addresses with a scheme or a port must be in brackets"}
+	}
+
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port != "" {
+		if number, err := strconv.Atoi(port); err != nil || number < 1 || number > 65535 {
+			return "", &EndpointError{Endpoint: endpoint, Reason: "port must be between 1 and 65535"}
+		}
+	}
+	if port != "" && !(u.Scheme == "http" && port == "80") && !(u.Scheme == "https" && port == "443") {
+		host += ":" + port
+	}
+
+	return u.Scheme + "://" + host + strings.TrimRight(u.EscapedPath(), "/"), nil
+}
+
+// schemePrefix matches the endpoints that start with a scheme
+var schemePrefix = regexp.MustCompile("^[a-zA-Z][a-zA-Z0-9+.-]*:/")
+
+// hostName returns the host name of an endpoint or of a URL, without scheme and port
+func hostName(endpoint string) string {
+	raw := endpoint
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return endpoint
+	}
+	return strings.ToLower(u.Hostname())
+}
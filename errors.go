@@ -0,0 +1,207 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * errors.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// The categories of the errors returned by GoFritzBox, check them with errors.Is
+// Every error caused by the Fritz!Box matches one of them, the others are network, file or input errors
+var (
+	// ErrAuth matches the errors caused by the authentication: wrong credentials, blocked logins, expired sessions
+	ErrAuth = errors.New("authentication failed")
+	// ErrPermission matches the errors caused by a user without the rights for the operation
+	ErrPermission = errors.New("permission denied")
+	// ErrUnsupported matches the errors caused by features not available on the model or the Fritz!OS version
+	ErrUnsupported = errors.New("not supported by this Fritz!Box")
+	// ErrBusy matches the errors caused by a Fritz!Box too busy to answer, retrying later can work
+	ErrBusy = errors.New("the Fritz!Box is busy")
+	// ErrProtocol matches the errors caused by answers that can't be understood
+	ErrProtocol = errors.New("unexpected answer from the Fritz!Box")
+)
+
+// categoryError is an error with a fixed message that belongs to a category
+type categoryError struct {
+	message  string
+	category error
+}
+
+func (e *categoryError) Error() string {
+	return e.message
+}
+
+func (e *categoryError) Unwrap() error {
+	return e.category
+}
+
+// ErrInvalidCredentials is returned when the Fritz!Box refuses the username or the password
+var ErrInvalidCredentials error = &categoryError{"invalid username or password", ErrAuth}
+
+// ErrTOTPRefused is returned when the Fritz!Box refuses the TOTP code of a TFAChallenge
+var ErrTOTPRefused error = &categoryError{"TOTP code refused", ErrAuth}
+
+// ErrTFANotPending is returned when a TFAChallenge expired or was refused before being confirmed
+var ErrTFANotPending error = &categoryError{"second factor confirmation is not pending anymore", ErrAuth}
+
+// LoginBlockedError is returned when the Fritz!Box doesn't accept logins for Wait, after wrong attempts
+// Err is ErrInvalidCredentials when the block was caused by the refused attempt itself,
+// nil when the Fritz!Box was already blocking logins and no credentials were sent
+type LoginBlockedError struct {
+	Wait time.Duration
+	Err  error
+}
+
+func (e *LoginBlockedError) Error() string {
+	message := fmt.Sprintf("failed to login, try again in %d second(s)", int(e.Wait/time.Second))
+	if e.Err != nil {
+		return e.Err.Error() + ", " + message
+	}
+	return message
+}
+
+func (e *LoginBlockedError) Unwrap() error {
+	return e.Err
+}
+
+func (e *LoginBlockedError) Is(target error) bool {
+	return target == ErrAuth
+}
+
+// ErrSessionExpired is returned when the Fritz!Box doesn't accept the SID anymore
+// Sessions created with Credentials login again instead of returning it
+var ErrSessionExpired error = &categoryError{"session expired", ErrAuth}
+
+// ErrUnexpectedResponse matches every UnexpectedResponseError with errors.Is
+var ErrUnexpectedResponse = errors.New("unexpected response from the Fritz!Box")
+
+// UnsupportedError is returned when the Fritz!Box doesn't offer Feature, it matches ErrUnsupported
+type UnsupportedError struct {
+	Feature string
+}
+
+func (e *UnsupportedError) Error() string {
+	return e.Feature + " is not supported by this Fritz!Box"
+}
+
+func (e *UnsupportedError) Is(target error) bool {
+	return target == ErrUnsupported
+}
+
+// ProtocolError is returned when the answer of Endpoint, the Fritz!Box page like data.lua, can't be understood
+// Err is the reason, usually a decoding error. It matches ErrProtocol
+type ProtocolError struct {
+	Endpoint string
+	Err      error
+}
+
+func (e *ProtocolError) Error() string {
+	return fmt.Sprintf("can't understand the answer of %s: %s", e.Endpoint, e.Err)
+}
+
+func (e *ProtocolError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ProtocolError) Is(target error) bool {
+	return target == ErrProtocol
+}
+
+// snippetLength is the maximum length of the body kept in an UnexpectedResponseError
+const snippetLength = 256
+
+// UnexpectedResponseError is returned when Endpoint answers with an error status,
+// or with a body that can't be decoded, like an HTML page instead of JSON
+// Snippet is the beginning of the body
+// Besides ErrUnexpectedResponse, it matches the category of its status: ErrPermission for 403,
+// ErrUnsupported for 404, ErrBusy for 502, 503 and 504, ErrProtocol for the others
+type UnexpectedResponseError struct {
+	Endpoint    string
+	StatusCode  int
+	ContentType string
+	Snippet     string
+}
+
+func newUnexpectedResponseError(response *Response) *UnexpectedResponseError {
+	snippet := response.Body
+	if len(snippet) > snippetLength {
+		snippet = snippet[:snippetLength]
+	}
+	return &UnexpectedResponseError{
+		Endpoint:    response.endpoint,
+		StatusCode:  response.StatusCode,
+		ContentType: response.Header.Get("Content-Type"),
+		Snippet:     string(snippet),
+	}
+}
+
+func (e *UnexpectedResponseError) Error() string {
+	return fmt.Sprintf("unexpected response from %s: status %d, %s: %q", e.Endpoint, e.StatusCode, e.ContentType, e.Snippet)
+}
+
+func (e *UnexpectedResponseError) Is(target error) bool {
+	return target == ErrUnexpectedResponse || target == e.category()
+}
+
+// category returns the category matching the status code
+func (e *UnexpectedResponseError) category() error {
+	switch e.StatusCode {
+	case http.StatusForbidden:
+		return ErrPermission
+	case http.StatusNotFound:
+		return ErrUnsupported
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return ErrBusy
+	default:
+		return ErrProtocol
+	}
+}
+
+// EndpointError is returned when an endpoint is not a valid Fritz!Box address, Reason tells why
+type EndpointError struct {
+	Endpoint string
+	Reason   string
+}
+
+func (e *EndpointError) Error() string {
+	return fmt.Sprintf("invalid endpoint %q: %s", e.Endpoint, e.Reason)
+}
+
+// CertificateMismatchError is returned when the Fritz!Box presents a certificate different from the pinned one
+// It can be a man in the middle, or a new certificate after a reset of the Fritz!Box: check it before rotating the pin
+type CertificateMismatchError struct {
+	Host     string
+	Pinned   string
+	Received string
+}
+
+func (e *CertificateMismatchError) Error() string {
+	return fmt.Sprintf("certificate of %s doesn't match the pinned one: pinned %s, received %s", e.Host, e.Pinned, e.Received)
+}
+
+// InsufficientRightsError is returned when the logged user lacks the Access needed for an operation
+type InsufficientRightsError struct {
+	Right    Right
+	Required Access
+	Granted  Access
+}
+
+func (e *InsufficientRightsError) Error() string {
+	return fmt.Sprintf("%s access to %s required, user has %s", e.Required, e.Right, e.Granted)
+}
+
+func (e *InsufficientRightsError) Is(target error) bool {
+	return target == ErrPermission
+}
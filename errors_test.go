@@ -0,0 +1,77 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * errors_test.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestErrorCategories(t *testing.T) {
+	categories := []error{ErrAuth, ErrPermission, ErrUnsupported, ErrBusy, ErrProtocol}
+	tests := []struct {
+		err  error
+		want error
+	}{
+		{ErrInvalidCredentials, ErrAuth},
+		{ErrSessionExpired, ErrAuth},
+		{ErrTOTPRefused, ErrAuth},
+		{ErrTFANotPending, ErrAuth},
+		{&LoginBlockedError{Wait: time.Second}, ErrAuth},
+		{&LoginBlockedError{Wait: time.Second, Err: ErrInvalidCredentials}, ErrAuth},
+		{&InsufficientRightsError{Right: "BoxAdmin", Required: AccessWrite}, ErrPermission},
+		{&UnsupportedError{Feature: "GetDSLInfo"}, ErrUnsupported},
+		{&ProtocolError{Endpoint: "data.lua", Err: errors.New("no log")}, ErrProtocol},
+		{&UnexpectedResponseError{StatusCode: http.StatusForbidden}, ErrPermission},
+		{&UnexpectedResponseError{StatusCode: http.StatusNotFound}, ErrUnsupported},
+		{&UnexpectedResponseError{StatusCode: http.StatusBadGateway}, ErrBusy},
+		{&UnexpectedResponseError{StatusCode: http.StatusServiceUnavailable}, ErrBusy},
+		{&UnexpectedResponseError{StatusCode: http.StatusGatewayTimeout}, ErrBusy},
+		{&UnexpectedResponseError{StatusCode: http.StatusOK}, ErrProtocol},
+		{&UnexpectedResponseError{StatusCode: http.StatusInternalServerError}, ErrProtocol},
+	}
+	for _, test := range tests {
+		for _, category := range categories {
+			if got := errors.Is(test.err, category); got != (category == test.want) {
+				t.Errorf("errors.Is(%v, %v) = %v", test.err, category, got)
+			}
+		}
+	}
+
+	if !errors.Is(&LoginBlockedError{Err: ErrInvalidCredentials}, ErrInvalidCredentials) {
+		t.Error("LoginBlockedError doesn't unwrap to ErrInvalidCredentials")
+	}
+	if !errors.Is(&UnexpectedResponseError{StatusCode: http.StatusNotFound}, ErrUnexpectedResponse) {
+		t.Error("UnexpectedResponseError doesn't match ErrUnexpectedResponse")
+	}
+}
+
+func TestErrorEndpoint(t *testing.T) {
+	session, _ := newFlakySession(t, 1, nil)
+
+	_, err := session.GetLogs()
+	var unexpected *UnexpectedResponseError
+	if !errors.As(err, &unexpected) || unexpected.Endpoint != "data.lua" {
+		t.Errorf("err = %v, want an UnexpectedResponseError of data.lua", err)
+	}
+	if !errors.Is(err, ErrBusy) {
+		t.Errorf("err = %v, want ErrBusy", err)
+	}
+
+	box := newFakeBox(t)
+	_, err = Login(box.server.URL+"/missing", "admin", fakePassword)
+	if !errors.Is(err, ErrUnsupported) || !errors.As(err, &unexpected) || unexpected.Endpoint != "login_sid.lua" {
+		t.Errorf("err = %v, want an ErrUnsupported UnexpectedResponseError of login_sid.lua", err)
+	}
+}
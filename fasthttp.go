@@ -12,7 +12,147 @@
 package GoFritzBox
 
 import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
 	"github.com/valyala/fasthttp"
 )
 
-var client = fasthttp.Client{}
+// FastHTTPTransport is a Transport using fasthttp, it's the default Transport of a Client
+// Client is the fasthttp.Client used for the requests
+type FastHTTPTransport struct {
+	Client *fasthttp.Client
+}
+
+// Do sends the request with fasthttp
+// fasthttp can't cancel a running request: when ctx is done Do returns and the request is abandoned,
+// it goes on until it's over or the ReadTimeout and WriteTimeout of the fasthttp.Client expire
+func (t *FastHTTPTransport) Do(ctx context.Context, request *Request) (*Response, error) {
+	return t.do(ctx, request, func() {})
+}
+
+// do is like Do, finished is called once fasthttp is done with the request, also when ctx made do return before
+func (t *FastHTTPTransport) do(ctx context.Context, request *Request, finished func()) (*Response, error) {
+	err := ctx.Err()
+	if err != nil {
+		finished()
+		return nil, err
+	}
+
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+
+	req.SetRequestURI(request.URL)
+	req.Header.SetMethod(request.Method)
+	for k, v := range request.Header {
+		for _, value := range v {
+			req.Header.Add(k, value)
+		}
+	}
+	if request.Body != nil {
+		req.SetBody(request.Body)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		// DoDeadline would return at the deadline while the request goes on inside fasthttp,
+		// Do returns when the request is really over, so finished is called at the right time
+		err := t.Client.Do(req, resp)
+		finished()
+		done <- err
+	}()
+
+	select {
+	case err = <-done:
+		defer func() {
+			fasthttp.ReleaseRequest(req)
+			fasthttp.ReleaseResponse(resp)
+		}()
+		deadline, ok := ctx.Deadline()
+		if err == fasthttp.ErrTimeout && ok && !time.Now().Before(deadline) {
+			// The fasthttp timeout expired together with the ctx deadline, report the deadline
+			return nil, context.DeadlineExceeded
+		} else if err != nil {
+			return nil, err
+		}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	response := &Response{
+		StatusCode: resp.StatusCode(),
+		Header:     http.Header{},
+		Body:       append([]byte(nil), resp.Body()...),
+	}
+	resp.Header.VisitAll(func(key, value []byte) {
+		response.Header.Add(string(key), string(value))
+	})
+	return response, nil
+}
+
+// newFastHTTPClient returns a fasthttp.Client configured as the Client
+func newFastHTTPClient(c *Client) *fasthttp.Client {
+	client := &fasthttp.Client{
+		ReadTimeout:     c.ReadTimeout,
+		WriteTimeout:    c.WriteTimeout,
+		MaxConnsPerHost: c.MaxConns,
+		TLSConfig:       c.TLSConfig,
+		// The RetryPolicy of the Client decides the retries, fasthttp would send again
+		// the GET requests that change the Fritz!Box state, like Disconnect
+		MaxIdemponentCallAttempts: 1,
+	}
+	if c.Proxy != "" {
+		client.Dial = proxyDialer(c.Proxy)
+	}
+	return client
+}
+
+// proxyDialer returns a fasthttp.DialFunc that opens the connections through an HTTP proxy with CONNECT
+func proxyDialer(proxy string) fasthttp.DialFunc {
+	proxy = strings.TrimPrefix(proxy, "http://")
+	var auth string
+	if i := strings.LastIndex(proxy, "@"); i != -1 {
+		auth = base64.StdEncoding.EncodeToString([]byte(proxy[:i]))
+		proxy = proxy[i+1:]
+	}
+
+	return func(addr string) (net.Conn, error) {
+		conn, err := fasthttp.Dial(proxy)
+		if err != nil {
+			return nil, err
+		}
+
+		request := "CONNECT " + addr + " HTTP/1.1\r\nHost: " + addr + "\r\n"
+		if auth != "" {
+			request += "Proxy-Authorization: Basic " + auth + "\r\n"
+		}
+		request += "\r\n"
+
+		_, err = conn.Write([]byte(request))
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+
+		response := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseResponse(response)
+		response.SkipBody = true
+
+		err = response.Read(bufio.NewReader(conn))
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if response.StatusCode() != fasthttp.StatusOK {
+			conn.Close()
+			return nil, fmt.Errorf("proxy %s refused the connection with status %d", proxy, response.StatusCode())
+		}
+		return conn, nil
+	}
+}
@@ -0,0 +1,80 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * fasthttp_test.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+)
+
+// startProxy starts an HTTP proxy that accepts CONNECT requests with the given Basic credentials
+func startProxy(t *testing.T, credentials string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveProxy(conn, credentials)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func serveProxy(conn net.Conn, credentials string) {
+	defer conn.Close()
+
+	request, err := http.ReadRequest(bufio.NewReader(conn))
+	if err != nil || request.Method != http.MethodConnect {
+		return
+	}
+	if request.Header.Get("Proxy-Authorization") != "Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)) {
+		io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\nContent-Length: 0\r\n\r\n")
+		return
+	}
+
+	target, err := net.Dial("tcp", request.Host)
+	if err != nil {
+		io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\nContent-Length: 0\r\n\r\n")
+		return
+	}
+	defer target.Close()
+
+	io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+	go io.Copy(target, conn)
+	io.Copy(conn, target)
+}
+
+func TestClientProxy(t *testing.T) {
+	box := newFakeBox(t)
+	proxy := startProxy(t, "user:secret")
+
+	client := &Client{Proxy: "http://user:secret@" + proxy}
+	if _, err := client.Login(box.server.URL, "admin", fakePassword); err != nil {
+		t.Fatal(err)
+	}
+
+	client = &Client{Proxy: "user:wrong@" + proxy}
+	if _, err := client.Login(box.server.URL, "admin", fakePassword); err == nil {
+		t.Error("Login succeeded through the proxy with wrong credentials")
+	}
+}
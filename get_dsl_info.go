@@ -12,34 +12,37 @@
 package GoFritzBox
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-
-	"github.com/valyala/fasthttp"
 )
 
+// GetDSLInfo returns ConnectionData about the DSL line
+// Fritz!Box without a DSL line return an UnsupportedError
 func (s *SessionInfo) GetDSLInfo() (*ConnectionData, error) {
-	request := fasthttp.AcquireRequest()
-	response := fasthttp.AcquireResponse()
-	defer func() {
-		fasthttp.ReleaseRequest(request)
-		fasthttp.ReleaseResponse(response)
-	}()
-
-	request.SetRequestURI(fmt.Sprintf("%s/data.lua", s.EndPoint))
-	request.SetBodyString(fmt.Sprintf("xhr=1&sid=%s&lang=it&page=dslOv&xhrId=all", s.SID))
-	request.Header.SetMethod(fasthttp.MethodPost)
+	return s.GetDSLInfoContext(context.Background())
+}
 
-	err := client.Do(request, response)
+// GetDSLInfoContext is like GetDSLInfo, ctx bounds the request and cancels it when done
+func (s *SessionInfo) GetDSLInfoContext(ctx context.Context) (*ConnectionData, error) {
+	response, err := s.do(ctx, readOnly, func(sid string) (*Request, error) {
+		return newPostRequest(
+			fmt.Sprintf("%s/data.lua", s.EndPoint),
+			"application/x-www-form-urlencoded",
+			[]byte(fmt.Sprintf("xhr=1&sid=%s&lang=%s&page=dslOv&xhrId=all", sid, s.lang())),
+		), nil
+	})
 	if err != nil {
 		return &ConnectionData{}, err
 	}
 
 	var result RequestData
-	err = json.Unmarshal(response.Body(), &result)
+	err = decodeJSON(response, &result)
 	if err != nil {
 		return &ConnectionData{}, err
 	}
+	if result.Data.ConnectionData == nil {
+		return &ConnectionData{}, &UnsupportedError{Feature: "GetDSLInfo"}
+	}
 
 	return result.Data.ConnectionData, nil
 }
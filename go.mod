@@ -5,5 +5,6 @@ go 1.15
 require (
 	github.com/andybalholm/brotli v1.0.3 // indirect
 	github.com/klauspost/compress v1.13.0 // indirect
-	github.com/valyala/fasthttp v1.26.0 // indirect
+	github.com/valyala/fasthttp v1.26.0
+	gopkg.in/yaml.v2 v2.4.0
 )
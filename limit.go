@@ -0,0 +1,126 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * limit.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limit protects the web server of a Fritz!Box from too many requests
+// MaxInFlight is the maximum number of concurrent requests, 1 serializes them, zero means no limit
+// RequestsPerSecond is the maximum rate of requests, zero means no limit
+// A request abandoned because its ctx is done keeps its slot until it's really over
+type Limit struct {
+	MaxInFlight       int
+	RequestsPerSecond float64
+}
+
+var (
+	limitersMutex sync.Mutex
+	limiters      = map[string]*limiter{}
+)
+
+// SetLimit limits the requests to the Fritz!Box at endpoint
+// The Limit is shared by every Client and session of the process that talks with the same host,
+// whatever the scheme or the port, so a dashboard and an exporter can't overload the Fritz!Box together
+// A zero Limit removes the limit
+func SetLimit(endpoint string, limit Limit) {
+	limitersMutex.Lock()
+	defer limitersMutex.Unlock()
+
+	host := hostName(endpoint)
+	if limit == (Limit{}) {
+		delete(limiters, host)
+		return
+	}
+	limiters[host] = newLimiter(limit)
+}
+
+// limiter enforces a Limit
+type limiter struct {
+	slots    chan struct{}
+	interval time.Duration
+
+	mutex sync.Mutex
+	next  time.Time
+}
+
+func newLimiter(limit Limit) *limiter {
+	l := &limiter{}
+	if limit.MaxInFlight > 0 {
+		l.slots = make(chan struct{}, limit.MaxInFlight)
+	}
+	if limit.RequestsPerSecond > 0 {
+		l.interval = time.Duration(float64(time.Second) / limit.RequestsPerSecond)
+	}
+	return l
+}
+
+// acquireLimit waits until a request to url can be sent, the returned function must be called when it's done
+func acquireLimit(ctx context.Context, url string) (func(), error) {
+	limitersMutex.Lock()
+	l := limiters[hostName(url)]
+	limitersMutex.Unlock()
+
+	if l == nil {
+		return func() {}, nil
+	}
+	return l.acquire(ctx)
+}
+
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	if l.interval > 0 {
+		// Every request books the first free time slot, then waits for it
+		l.mutex.Lock()
+		now := time.Now()
+		if l.next.Before(now) {
+			l.next = now
+		}
+		slot := l.next
+		l.next = l.next.Add(l.interval)
+		l.mutex.Unlock()
+
+		wait := time.Until(slot)
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				l.cancel(slot)
+				return nil, ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+
+	if l.slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, nil
+	}
+}
+
+// cancel releases the time slot booked by a request that stopped waiting for it
+// Only the last booked slot can be given back, the requests after it keep their time
+func (l *limiter) cancel(slot time.Time) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.next.Equal(slot.Add(l.interval)) {
+		l.next = slot
+	}
+}
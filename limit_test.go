@@ -0,0 +1,162 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * limit_test.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newCountingServer returns a server that answers after delay, counting the highest number of concurrent requests
+func newCountingServer(t *testing.T, delay time.Duration) (*httptest.Server, *int32) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(delay)
+		atomic.AddInt32(&inFlight, -1)
+	}))
+	t.Cleanup(server.Close)
+	return server, &maxInFlight
+}
+
+func TestLimitMaxInFlight(t *testing.T) {
+	server, maxInFlight := newCountingServer(t, 20*time.Millisecond)
+	SetLimit(server.URL, Limit{MaxInFlight: 1})
+	defer SetLimit(server.URL, Limit{})
+
+	client := &Client{}
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.do(context.Background(), readOnly, newGetRequest(server.URL)); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(maxInFlight); n != 1 {
+		t.Errorf("%d requests in flight, want 1", n)
+	}
+}
+
+func TestLimitRequestsPerSecond(t *testing.T) {
+	server, _ := newCountingServer(t, 0)
+	SetLimit(server.URL, Limit{RequestsPerSecond: 20})
+	defer SetLimit(server.URL, Limit{})
+
+	client := &Client{}
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if _, err := client.do(context.Background(), readOnly, newGetRequest(server.URL)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The first request is sent at once, the others 50ms apart
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("4 requests took %v, want at least 150ms", elapsed)
+	}
+
+	// Removing the limit sends the requests at once
+	SetLimit(server.URL, Limit{})
+	start = time.Now()
+	for i := 0; i < 4; i++ {
+		if _, err := client.do(context.Background(), readOnly, newGetRequest(server.URL)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed >= 150*time.Millisecond {
+		t.Errorf("4 requests without limit took %v", elapsed)
+	}
+}
+
+func TestLimitSharedByHost(t *testing.T) {
+	SetLimit("http://fritz.box", Limit{MaxInFlight: 1})
+	defer SetLimit("fritz.box", Limit{})
+
+	release, err := acquireLimit(context.Background(), "https://fritz.box:443/data.lua")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := acquireLimit(ctx, "http://fritz.box/login_sid.lua"); err != context.DeadlineExceeded {
+		t.Errorf("err = %v, want context.DeadlineExceeded while the slot is taken", err)
+	}
+
+	release()
+	release, err = acquireLimit(context.Background(), "http://fritz.box/login_sid.lua")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+}
+
+// An abandoned fasthttp request must keep its slot until the Fritz!Box answered it
+func TestLimitAbandonedRequest(t *testing.T) {
+	server, maxInFlight := newCountingServer(t, 200*time.Millisecond)
+	SetLimit(server.URL, Limit{MaxInFlight: 1})
+	defer SetLimit(server.URL, Limit{})
+
+	client := &Client{}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.do(ctx, readOnly, newGetRequest(server.URL+"/slow")); err != context.DeadlineExceeded {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if _, err := client.do(context.Background(), readOnly, newGetRequest(server.URL+"/next")); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := atomic.LoadInt32(maxInFlight); n != 1 {
+		t.Errorf("%d requests in flight, want 1", n)
+	}
+}
+
+// A request that stops waiting for its time slot gives it back to the next one
+func TestLimitCancelledWait(t *testing.T) {
+	SetLimit("fritz.box", Limit{RequestsPerSecond: 5})
+	defer SetLimit("fritz.box", Limit{})
+
+	start := time.Now()
+	if _, err := acquireLimit(context.Background(), "http://fritz.box"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := acquireLimit(ctx, "http://fritz.box"); err != context.DeadlineExceeded {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if _, err := acquireLimit(ctx, "http://fritz.box"); err != nil {
+		t.Fatalf("err = %v, want the slot given back by the cancelled wait", err)
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("the next request waited %v, want at least 200ms", elapsed)
+	}
+}
@@ -12,36 +12,35 @@
 package GoFritzBox
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-
-	"github.com/valyala/fasthttp"
 )
 
 // LoadInfo returns general Data about the Fritz!Box
 func (s *SessionInfo) LoadInfo() (Data, error) {
-	request := fasthttp.AcquireRequest()
-	response := fasthttp.AcquireResponse()
-	defer func() {
-		fasthttp.ReleaseRequest(request)
-		fasthttp.ReleaseResponse(response)
-	}()
-
-	request.SetRequestURI(fmt.Sprintf(
-		"%s/data.lua?sid=%s&xhr=1&lang=it&page=overview&xhrId=first&noMenuRef=1&no_sidrenew=",
-		s.EndPoint, s.SID,
-	))
+	return s.LoadInfoContext(context.Background())
+}
 
-	err := client.Do(request, response)
+// LoadInfoContext is like LoadInfo, ctx bounds the request and cancels it when done
+func (s *SessionInfo) LoadInfoContext(ctx context.Context) (Data, error) {
+	response, err := s.do(ctx, readOnly, func(sid string) (*Request, error) {
+		return newGetRequest(fmt.Sprintf(
+			"%s/data.lua?sid=%s&xhr=1&lang=%s&page=overview&xhrId=first&noMenuRef=1&no_sidrenew=",
+			s.EndPoint, sid, s.lang(),
+		)), nil
+	})
 	if err != nil {
 		return Data{}, err
 	}
 
 	var result RequestData
-	err = json.Unmarshal(response.Body(), &result)
+	err = decodeJSON(response, &result)
 	if err != nil {
 		return Data{}, err
 	}
 
+	if result.Data.Internet != nil {
+		result.Data.Internet.Lang = s.lang()
+	}
 	return result.Data, nil
 }
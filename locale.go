@@ -0,0 +1,72 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * locale.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Locale describes how the Fritz!Box writes the text of a language, it's used by the text parsers
+// DateTime is the time layout of dates with time, written with the one-or-two digits elements 2, 1 and 15
+// LogTime is the time layout of the date and time columns of the logs, joined by a space
+// Decimal and Thousands are the separators of the numbers
+// ConnectedSince precedes the connection time, Provider is the label of the provider name
+type Locale struct {
+	DateTime       string
+	LogTime        string
+	Decimal        string
+	Thousands      string
+	ConnectedSince string
+	Provider       string
+}
+
+// Locales contains the Locale of every language of the Fritz!OS, keyed by the session Lang
+// Add or replace entries to support other languages or firmwares
+var Locales = map[string]Locale{
+	"de": {DateTime: "2.1.2006, 15:04", LogTime: "2.1.06 15:04:05", Decimal: ",", Thousands: ".", ConnectedSince: "verbunden seit", Provider: "Anbieter"},
+	"en": {DateTime: "2.1.2006, 15:04", LogTime: "2.1.06 15:04:05", Decimal: ".", Thousands: ",", ConnectedSince: "connected since", Provider: "Provider"},
+	"it": {DateTime: "2.1.2006, 15:04", LogTime: "2.1.06 15:04:05", Decimal: ",", Thousands: ".", ConnectedSince: "connesso da", Provider: "Provider"},
+	"fr": {DateTime: "2.1.2006, 15:04", LogTime: "2.1.06 15:04:05", Decimal: ",", Thousands: " ", ConnectedSince: "connecté depuis", Provider: "Fournisseur"},
+	"es": {DateTime: "2.1.2006, 15:04", LogTime: "2.1.06 15:04:05", Decimal: ",", Thousands: ".", ConnectedSince: "conectado desde", Provider: "Proveedor"},
+	"nl": {DateTime: "2.1.2006, 15:04", LogTime: "2.1.06 15:04:05", Decimal: ",", Thousands: ".", ConnectedSince: "verbonden sinds", Provider: "Provider"},
+	"pl": {DateTime: "2.1.2006, 15:04", LogTime: "2.1.06 15:04:05", Decimal: ",", Thousands: " ", ConnectedSince: "połączono od", Provider: "Dostawca"},
+}
+
+// LocaleFor returns the Locale of lang, the one of DefaultLang if lang is unknown
+func LocaleFor(lang string) Locale {
+	if locale, ok := Locales[strings.ToLower(lang)]; ok {
+		return locale
+	}
+	return Locales[DefaultLang]
+}
+
+// layoutPattern returns a regexp that finds the text written with a time layout
+func layoutPattern(layout string) *regexp.Regexp {
+	return regexp.MustCompile(strings.NewReplacer(
+		"2006", "[0-9]{4}",
+		"06", "[0-9]{2}",
+		"15", "[0-9]{1,2}",
+		"04", "[0-9]{2}",
+		"05", "[0-9]{2}",
+		"2", "[0-9]{1,2}",
+		"1", "[0-9]{1,2}",
+	).Replace(regexp.QuoteMeta(layout)))
+}
+
+// parseNumber converts a number written in the Locale to the format of strconv
+func (l Locale) parseNumber(number string) string {
+	if l.Thousands != "" {
+		number = strings.ReplaceAll(number, l.Thousands, "")
+	}
+	return strings.ReplaceAll(number, l.Decimal, ".")
+}
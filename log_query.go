@@ -0,0 +1,127 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * log_query.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LogEntries is a list of LogEntry, as returned by GetLogEntries
+type LogEntries []LogEntry
+
+// LogQuery selects LogEntry, an entry matches when it matches every field that is set
+// Categories and MessageIDs match any of their values, LogAll in Categories matches every entry
+// Since is inclusive and Until exclusive,
+// Contains is a case-insensitive substring of the message and Pattern a regexp on it
+type LogQuery struct {
+	Categories []LogCategory
+	MessageIDs []int
+	Since      time.Time
+	Until      time.Time
+	Contains   string
+	Pattern    *regexp.Regexp
+}
+
+// Matches reports whether the entry is selected by the LogQuery
+func (q LogQuery) Matches(entry LogEntry) bool {
+	if len(q.Categories) > 0 && !containsCategory(q.Categories, entry.Category) {
+		return false
+	}
+	if len(q.MessageIDs) > 0 && !containsInt(q.MessageIDs, entry.MessageID) {
+		return false
+	}
+	if !q.Since.IsZero() && entry.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !entry.Time.Before(q.Until) {
+		return false
+	}
+	if q.Contains != "" && !strings.Contains(strings.ToLower(entry.Message), strings.ToLower(q.Contains)) {
+		return false
+	}
+	if q.Pattern != nil && !q.Pattern.MatchString(entry.Message) {
+		return false
+	}
+	return true
+}
+
+// Query returns the entries selected by the LogQuery
+func (l LogEntries) Query(q LogQuery) LogEntries {
+	var result LogEntries
+	for _, entry := range l {
+		if q.Matches(entry) {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// GroupBy groups the entries by the key returned for each of them, like LogDay or LogMessageID
+func (l LogEntries) GroupBy(key func(LogEntry) string) map[string]LogEntries {
+	groups := make(map[string]LogEntries)
+	for _, entry := range l {
+		k := key(entry)
+		groups[k] = append(groups[k], entry)
+	}
+	return groups
+}
+
+// CountBy counts the messages for each key returned for the entries, including their Repeat
+// For example, the DSL resyncs per day are
+// entries.Query(LogQuery{Contains: "DSL"}).CountBy(LogDay)
+func (l LogEntries) CountBy(key func(LogEntry) string) map[string]int {
+	counts := make(map[string]int)
+	for _, entry := range l {
+		repeat := entry.Repeat
+		if repeat < 1 {
+			repeat = 1
+		}
+		counts[key(entry)] += repeat
+	}
+	return counts
+}
+
+// LogDay is a key for GroupBy and CountBy, the day of the entry as 2006-01-02
+func LogDay(entry LogEntry) string {
+	return entry.Time.Format("2006-01-02")
+}
+
+// LogCategoryKey is a key for GroupBy and CountBy, the name of the entry category
+func LogCategoryKey(entry LogEntry) string {
+	return entry.Category.String()
+}
+
+// LogMessageID is a key for GroupBy and CountBy, the message ID of the entry
+func LogMessageID(entry LogEntry) string {
+	return strconv.Itoa(entry.MessageID)
+}
+
+func containsCategory(categories []LogCategory, category LogCategory) bool {
+	for _, c := range categories {
+		if c == category || c == LogAll {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * log_query_test.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestLogQuery(t *testing.T) {
+	entries := Logs{
+		{"07.03.21", "12:00:01", "DSL resync [3 Meldungen seit 07.03.21 11:00:00]", "23", "2"},
+		{"08.03.21", "09:01:02", "DSL resync", "23", "2"},
+		{"08.03.21", "10:00:00", "WLAN up", "5", "4"},
+		{"08.03.21", "11:00:00", "USB stick removed", "40", "5"},
+		{"08.03.21", "12:00:00", "no category"},
+	}.Entries("de")
+
+	tests := []struct {
+		name  string
+		query LogQuery
+		want  int
+	}{
+		{"everything", LogQuery{}, 5},
+		{"all categories", LogQuery{Categories: []LogCategory{LogAll}}, 5},
+		{"categories", LogQuery{Categories: []LogCategory{LogWiFi, LogUSB}}, 2},
+		{"unknown", LogQuery{Categories: []LogCategory{LogUnknown}}, 1},
+		{"message id and since", LogQuery{MessageIDs: []int{23}, Since: time.Date(2021, 3, 8, 0, 0, 0, 0, time.Local)}, 1},
+		{"until", LogQuery{Until: time.Date(2021, 3, 8, 0, 0, 0, 0, time.Local)}, 1},
+		{"since is inclusive", LogQuery{Since: time.Date(2021, 3, 8, 10, 0, 0, 0, time.Local)}, 3},
+		{"contains", LogQuery{Contains: "dsl"}, 2},
+		{"pattern", LogQuery{Pattern: regexp.MustCompile("^WLAN")}, 1},
+	}
+	for _, test := range tests {
+		if got := len(entries.Query(test.query)); got != test.want {
+			t.Errorf("%s: %d entries, want %d", test.name, got, test.want)
+		}
+	}
+
+	perDay := entries.Query(LogQuery{Contains: "DSL resync"}).CountBy(LogDay)
+	if perDay["2021-03-07"] != 3 || perDay["2021-03-08"] != 1 {
+		t.Errorf("DSL resyncs per day = %v", perDay)
+	}
+	if groups := entries.GroupBy(LogCategoryKey); len(groups["internet"]) != 2 || len(groups["usb"]) != 1 || len(groups["unknown"]) != 1 {
+		t.Errorf("groups = %v", groups)
+	}
+	if counts := entries.CountBy(LogMessageID); counts["23"] != 4 || counts["5"] != 1 {
+		t.Errorf("counts by message ID = %v", counts)
+	}
+}
@@ -12,58 +12,210 @@
 package GoFritzBox
 
 import (
-	"encoding/xml"
+	"context"
 	"errors"
 	"fmt"
-	"strconv"
-
-	"github.com/valyala/fasthttp"
+	"net/url"
+	"time"
 )
 
 // Login does a login on the Fritz!Box
+// endpoint is normalized by NormalizeEndpoint, so fritz.box, http://192.168.178.1/ and
+// https://xyz.myfritz.net:port are all valid
+// When username is empty, the last user that logged in is used
+// The PBKDF2 challenge (login version 2) is used when the Fritz!OS offers it, MD5 otherwise
 // Returns SessionInfo in case of success
 func Login(endpoint, username, password string) (SessionInfo, error) {
-	request := fasthttp.AcquireRequest()
-	response := fasthttp.AcquireResponse()
+	return DefaultClient.Login(endpoint, username, password)
+}
+
+// LoginContext is like Login, ctx bounds the requests and cancels them when done
+func LoginContext(ctx context.Context, endpoint, username, password string) (SessionInfo, error) {
+	return DefaultClient.LoginContext(ctx, endpoint, username, password)
+}
+
+// Login does a login on the Fritz!Box like the Login function, the session uses the Client for its requests
+func (c *Client) Login(endpoint, username, password string) (SessionInfo, error) {
+	return c.LoginContext(context.Background(), endpoint, username, password)
+}
+
+// LoginContext is like Login, ctx bounds the requests and cancels them when done
+func (c *Client) LoginContext(ctx context.Context, endpoint, username, password string) (SessionInfo, error) {
+	return c.login(ctx, endpoint, username, password)
+}
+
+// LoginWait does a login on the Fritz!Box like Login, but when the Fritz!Box is blocking logins
+// it waits for the BlockTime and tries again, until the login succeeds or ctx is done
+// Wrong credentials are not retried, the error is returned as it is
+func LoginWait(ctx context.Context, endpoint, username, password string) (SessionInfo, error) {
+	return DefaultClient.LoginWait(ctx, endpoint, username, password)
+}
+
+// LoginWait does a login on the Fritz!Box like the LoginWait function, the session uses the Client for its requests
+func (c *Client) LoginWait(ctx context.Context, endpoint, username, password string) (SessionInfo, error) {
+	for {
+		session, err := c.login(ctx, endpoint, username, password)
+		var blocked *LoginBlockedError
+		if !errors.As(err, &blocked) || blocked.Err != nil {
+			return session, err
+		}
+
+		timer := time.NewTimer(blocked.Wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return session, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// LoginWithCredentials does a login on the Fritz!Box like Login, keeping the Credentials in the session
+// When the Fritz!Box invalidates the SID, the session logs in again with the Credentials
+// and retries the failed request once, calling OnRelogin if it's set
+func LoginWithCredentials(endpoint string, credentials Credentials) (SessionInfo, error) {
+	return DefaultClient.LoginWithCredentials(endpoint, credentials)
+}
+
+// LoginWithCredentialsContext is like LoginWithCredentials, ctx bounds the requests and cancels them when done
+func LoginWithCredentialsContext(ctx context.Context, endpoint string, credentials Credentials) (SessionInfo, error) {
+	return DefaultClient.LoginWithCredentialsContext(ctx, endpoint, credentials)
+}
 
-	request.SetRequestURI(endpoint + "/login_sid.lua")
+// LoginWithCredentials does a login on the Fritz!Box like the LoginWithCredentials function,
+// the session uses the Client for its requests
+func (c *Client) LoginWithCredentials(endpoint string, credentials Credentials) (SessionInfo, error) {
+	return c.LoginWithCredentialsContext(context.Background(), endpoint, credentials)
+}
 
-	err := client.Do(request, response)
+// LoginWithCredentialsContext is like LoginWithCredentials, ctx bounds the requests and cancels them when done
+func (c *Client) LoginWithCredentialsContext(ctx context.Context, endpoint string, credentials Credentials) (SessionInfo, error) {
+	username, password, err := credentials()
 	if err != nil {
 		return SessionInfo{}, err
 	}
 
-	var prelogin SessionInfo
-	err = xml.Unmarshal(response.Body(), &prelogin)
+	session, err := c.login(ctx, endpoint, username, password)
 	if err != nil {
-		return SessionInfo{}, err
+		return session, err
 	}
+	session.credentials = credentials
+	return session, nil
+}
 
-	fasthttp.ReleaseRequest(request)
-	fasthttp.ReleaseResponse(response)
+// relogin replaces the SID of the session with a new one obtained with its Credentials
+func (s *SessionInfo) relogin(ctx context.Context) error {
+	if s.credentials == nil {
+		return errors.New("session has no credentials to login again")
+	}
 
-	request = fasthttp.AcquireRequest()
-	response = fasthttp.AcquireResponse()
+	username, password, err := s.credentials()
+	if err != nil {
+		return err
+	}
 
-	request.SetRequestURI(fmt.Sprintf(
-		"%s/login_sid.lua?response=%s-%s&username=%s",
-		endpoint, prelogin.Challenge, preparePassword(prelogin.Challenge, password), username,
-	))
-	err = client.Do(request, response)
+	session, err := s.client().login(ctx, s.EndPoint, username, password)
+	if err != nil {
+		return err
+	}
+
+	guard := s.lock()
+	guard.mu.Lock()
+	s.SID = session.SID
+	s.Challenge = session.Challenge
+	s.BlockTime = session.BlockTime
+	s.Rights = session.Rights
+	s.Created = session.Created
+	guard.mu.Unlock()
+	return nil
+}
+
+// ListUsers returns the usernames configured on the Fritz!Box and the last one that logged in,
+// which is the default user of boxes configured for password-only login
+func ListUsers(endpoint string) ([]string, string, error) {
+	return DefaultClient.ListUsers(endpoint)
+}
+
+// ListUsersContext is like ListUsers, ctx bounds the request and cancels it when done
+func ListUsersContext(ctx context.Context, endpoint string) ([]string, string, error) {
+	return DefaultClient.ListUsersContext(ctx, endpoint)
+}
+
+// ListUsers returns the users of the Fritz!Box like the ListUsers function, using the Client
+func (c *Client) ListUsers(endpoint string) ([]string, string, error) {
+	return c.ListUsersContext(context.Background(), endpoint)
+}
+
+// ListUsersContext is like ListUsers, ctx bounds the request and cancels it when done
+func (c *Client) ListUsersContext(ctx context.Context, endpoint string) ([]string, string, error) {
+	endpoint, err := NormalizeEndpoint(endpoint)
+	if err != nil {
+		return nil, "", err
+	}
+
+	prelogin, err := c.getSessionInfo(ctx, readOnly, endpoint+"/login_sid.lua?version=2")
+	if err != nil {
+		return nil, "", err
+	}
+
+	var users []string
+	for _, user := range prelogin.Users {
+		users = append(users, user.Name)
+	}
+	return users, prelogin.lastUser(), nil
+}
+
+// lastUser returns the user flagged as last in the pre-login answer, empty if there's none
+func (s *SessionInfo) lastUser() string {
+	for _, user := range s.Users {
+		if user.Last {
+			return user.Name
+		}
+	}
+	return ""
+}
+
+func (c *Client) login(ctx context.Context, endpoint, username, password string) (SessionInfo, error) {
+	endpoint, err := NormalizeEndpoint(endpoint)
+	if err != nil {
+		return SessionInfo{}, err
+	}
+
+	prelogin, err := c.getSessionInfo(ctx, readOnly, endpoint+"/login_sid.lua?version=2")
 	if err != nil {
 		return SessionInfo{}, err
 	}
 
-	var login SessionInfo
-	err = xml.Unmarshal(response.Body(), &login)
+	if prelogin.BlockTime > 0 {
+		return prelogin, &LoginBlockedError{Wait: time.Duration(prelogin.BlockTime) * time.Second}
+	}
+
+	if username == "" {
+		username = prelogin.lastUser()
+	}
+
+	challengeResponse, err := prepareResponse(prelogin.Challenge, password)
+	if err != nil {
+		return SessionInfo{}, &ProtocolError{Endpoint: "login_sid.lua", Err: err}
+	}
+
+	login, err := c.getSessionInfo(ctx, changesState, fmt.Sprintf(
+		"%s/login_sid.lua?version=2&response=%s&username=%s",
+		endpoint, url.QueryEscape(challengeResponse), url.QueryEscape(username),
+	))
 	if err != nil {
 		return SessionInfo{}, err
 	}
 
-	if login.SID != "0000000000000000" {
+	if login.SID != invalidSID {
 		login.EndPoint = endpoint
+		login.Client = c
+		login.Created = time.Now()
+		login.Lang = DefaultLang
 		return login, nil
+	} else if login.BlockTime > 0 {
+		return login, &LoginBlockedError{Wait: time.Duration(login.BlockTime) * time.Second, Err: ErrInvalidCredentials}
 	} else {
-		return login, errors.New("failed to login, try again in " + strconv.Itoa(login.BlockTime) + " second(s)")
+		return login, ErrInvalidCredentials
 	}
 }
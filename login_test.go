@@ -0,0 +1,357 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * login_test.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	fakePassword  = "1example!"
+	fakeChallenge = "2$10$5A1711$20$5A1722"
+)
+
+// fakeBox is a Fritz!Box answering to login_sid.lua and to the overview and log pages of data.lua
+// blocks prelogins answer with blockTime, the refused logins with failBlockTime
+// langs are the langs of the data.lua requests, userAgent the User-Agent of the last request
+type fakeBox struct {
+	server        *httptest.Server
+	challenge     string
+	blocks        int
+	blockTime     int
+	failBlockTime int
+
+	mutex     sync.Mutex
+	sid       string
+	count     int
+	logins    int
+	langs     []string
+	userAgent string
+}
+
+func newFakeBox(t *testing.T) *fakeBox {
+	box := &fakeBox{challenge: fakeChallenge}
+	box.server = httptest.NewServer(http.HandlerFunc(box.handle))
+	t.Cleanup(box.server.Close)
+	return box
+}
+
+// currentSID returns the SID accepted by the fakeBox
+func (b *fakeBox) currentSID() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.sid
+}
+
+// expire invalidates the SID, like the Fritz!Box does after some idle time
+func (b *fakeBox) expire() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.sid = ""
+}
+
+func (b *fakeBox) handle(w http.ResponseWriter, r *http.Request) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	_ = r.ParseForm()
+	b.userAgent = r.UserAgent()
+	switch r.URL.Path {
+	case "/login_sid.lua":
+		b.loginSID(w, r)
+	case "/data.lua":
+		b.data(w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (b *fakeBox) loginSID(w http.ResponseWriter, r *http.Request) {
+	answer, blockTime := invalidSID, 0
+	sid := r.Form.Get("sid")
+	expected, _ := prepareResponse(b.challenge, fakePassword)
+	switch {
+	case r.Form.Get("logout") != "":
+		b.sid = ""
+	case r.Form.Get("response") == expected && r.Form.Get("username") == "admin":
+		b.count++
+		b.logins++
+		b.sid = fmt.Sprintf("%016d", b.count)
+		answer = b.sid
+	case r.Form.Get("response") != "":
+		blockTime = b.failBlockTime
+	case sid != "":
+		if sid == b.sid {
+			answer = b.sid
+		}
+	case b.blocks > 0:
+		b.blocks--
+		blockTime = b.blockTime
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?><SessionInfo><SID>%s</SID>`+
+		`<Challenge>%s</Challenge><BlockTime>%d</BlockTime>`+
+		`<Rights><Name>BoxAdmin</Name><Access>2</Access><Name>NAS</Name><Access>1</Access></Rights>`+
+		`<Users><User>guest</User><User last="1">admin</User></Users></SessionInfo>`, answer, b.challenge, blockTime)
+}
+
+// loginCount returns the number of successful logins
+func (b *fakeBox) loginCount() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.logins
+}
+
+func (b *fakeBox) data(w http.ResponseWriter, r *http.Request) {
+	sid := r.Form.Get("sid")
+	if sid == "" || sid != b.sid {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html>login</html>")
+		return
+	}
+
+	lang := r.Form.Get("lang")
+	b.langs = append(b.langs, lang)
+	w.Header().Set("Content-Type", "application/json")
+	switch r.Form.Get("page") {
+	case "overview":
+		fmt.Fprintf(w, `{"pid":"overview","sid":%q,"data":{"language":%q}}`, sid, lang)
+	default:
+		fmt.Fprintf(w, `{"pid":"log","sid":%q,"data":{"log":[["18.10.21","10:00:00","DSL ist verfügbar","23","2","help"]]}}`, sid)
+	}
+}
+
+// lastLang returns the lang of the last data.lua request
+func (b *fakeBox) lastLang() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if len(b.langs) == 0 {
+		return ""
+	}
+	return b.langs[len(b.langs)-1]
+}
+
+func TestLogin(t *testing.T) {
+	for _, challenge := range []string{fakeChallenge, "1234567z"} {
+		box := newFakeBox(t)
+		box.challenge = challenge
+
+		session, err := Login(box.server.URL, "admin", fakePassword)
+		if err != nil {
+			t.Errorf("challenge %s: %v", challenge, err)
+			continue
+		}
+		if session.SID != box.currentSID() || session.EndPoint != box.server.URL {
+			t.Errorf("challenge %s: got SID %q and EndPoint %q", challenge, session.SID, session.EndPoint)
+		}
+		if !session.Rights.CanWrite(RightBoxAdmin) || session.Rights.CanWrite(RightNAS) || !session.Rights.CanRead(RightNAS) {
+			t.Errorf("challenge %s: Rights = %v, want BoxAdmin write and NAS read", challenge, session.Rights)
+		}
+	}
+}
+
+func TestLoginLastUser(t *testing.T) {
+	box := newFakeBox(t)
+
+	session, err := Login(box.server.URL, "", fakePassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.SID != box.currentSID() {
+		t.Errorf("SID = %q, want %q", session.SID, box.currentSID())
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	box := newFakeBox(t)
+
+	users, last, err := ListUsers(box.server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[0] != "guest" || users[1] != "admin" || last != "admin" {
+		t.Errorf("ListUsers() = %v, %q, want [guest admin], admin", users, last)
+	}
+}
+
+func TestLoginInvalidCredentials(t *testing.T) {
+	box := newFakeBox(t)
+
+	_, err := Login(box.server.URL, "admin", "wrong")
+	if !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("err = %v, want ErrInvalidCredentials", err)
+	}
+
+	box.failBlockTime = 10
+	_, err = Login(box.server.URL, "admin", "wrong")
+	var blocked *LoginBlockedError
+	if !errors.As(err, &blocked) || blocked.Wait != 10*time.Second || !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("err = %v, want a LoginBlockedError of 10s caused by ErrInvalidCredentials", err)
+	}
+}
+
+func TestLoginBlocked(t *testing.T) {
+	box := newFakeBox(t)
+	box.blocks, box.blockTime = 1, 5
+
+	_, err := Login(box.server.URL, "admin", fakePassword)
+	var blocked *LoginBlockedError
+	if !errors.As(err, &blocked) || blocked.Wait != 5*time.Second || blocked.Err != nil {
+		t.Errorf("err = %v, want a LoginBlockedError of 5s", err)
+	}
+}
+
+func TestLoginWait(t *testing.T) {
+	box := newFakeBox(t)
+	box.blocks, box.blockTime = 1, 1
+
+	session, err := LoginWait(context.Background(), box.server.URL, "admin", fakePassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.SID != box.currentSID() {
+		t.Errorf("SID = %q, want %q", session.SID, box.currentSID())
+	}
+
+	box.blocks = 100
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := LoginWait(ctx, box.server.URL, "admin", fakePassword); err != context.DeadlineExceeded {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestSessionExpired(t *testing.T) {
+	box := newFakeBox(t)
+
+	session, err := Login(box.server.URL, "admin", fakePassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	box.expire()
+
+	_, err = session.GetLogs()
+	if !errors.Is(err, ErrSessionExpired) {
+		t.Errorf("err = %v, want ErrSessionExpired", err)
+	}
+}
+
+func TestRelogin(t *testing.T) {
+	box := newFakeBox(t)
+
+	session, err := LoginWithCredentials(box.server.URL, StaticCredentials("admin", fakePassword))
+	if err != nil {
+		t.Fatal(err)
+	}
+	relogins := 0
+	session.OnRelogin = func(s *SessionInfo) {
+		relogins++
+	}
+	oldSID := session.SID
+	box.expire()
+
+	logs, err := session.GetLogs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 1 {
+		t.Errorf("len(logs) = %d, want 1", len(logs))
+	}
+	if session.SID == oldSID || session.SID != box.currentSID() {
+		t.Errorf("SID = %q, want the new SID %q", session.SID, box.currentSID())
+	}
+	if relogins != 1 || box.loginCount() != 2 {
+		t.Errorf("%d OnRelogin calls and %d logins, want 1 and 2", relogins, box.loginCount())
+	}
+}
+
+func TestConcurrentRelogin(t *testing.T) {
+	box := newFakeBox(t)
+
+	session, err := LoginWithCredentials(box.server.URL, StaticCredentials("admin", fakePassword))
+	if err != nil {
+		t.Fatal(err)
+	}
+	box.expire()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := session.GetLogs(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if logins := box.loginCount(); logins != 2 {
+		t.Errorf("%d logins, want the first one and a single re-login", logins)
+	}
+}
+
+func TestReloginHookUsesSession(t *testing.T) {
+	box := newFakeBox(t)
+
+	session, err := LoginWithCredentials(box.server.URL, StaticCredentials("admin", fakePassword))
+	if err != nil {
+		t.Fatal(err)
+	}
+	relogins := 0
+	session.OnRelogin = func(s *SessionInfo) {
+		relogins++
+		if relogins == 1 {
+			// The SID expires again, the hook has to login again too
+			box.expire()
+			if _, err := s.GetLogs(); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+	box.expire()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := session.GetLogs()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("a session request inside OnRelogin deadlocked")
+	}
+	if relogins != 2 || box.loginCount() != 3 {
+		t.Errorf("%d OnRelogin calls and %d logins, want 2 and 3", relogins, box.loginCount())
+	}
+}
+
+func TestSessionGuard(t *testing.T) {
+	first, second := &SessionInfo{}, &SessionInfo{}
+	if first.lock() != first.lock() {
+		t.Error("the session got a new sessionGuard on every call")
+	}
+	if first.lock() == second.lock() {
+		t.Error("two sessions share the same sessionGuard")
+	}
+}
@@ -0,0 +1,42 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * logout.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Logout ends the session on the Fritz!Box, freeing its slot in the session table
+// The SID can't be used anymore after a successful Logout
+func (s *SessionInfo) Logout() error {
+	return s.LogoutContext(context.Background())
+}
+
+// LogoutContext is like Logout, ctx bounds the request and cancels it when done
+func (s *SessionInfo) LogoutContext(ctx context.Context) error {
+	result, err := s.client().getSessionInfo(ctx, changesState, fmt.Sprintf(
+		"%s/login_sid.lua?version=2&logout=1&sid=%s",
+		s.EndPoint, s.sid(),
+	))
+	if err != nil {
+		return err
+	}
+
+	if result.SID == invalidSID {
+		s.setSID(invalidSID)
+		return nil
+	} else {
+		return &ProtocolError{Endpoint: "login_sid.lua", Err: errors.New("failed to logout")}
+	}
+}
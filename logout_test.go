@@ -0,0 +1,42 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * logout_test.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import "testing"
+
+func TestCheckAndLogout(t *testing.T) {
+	box := newFakeBox(t)
+
+	session, err := Login(box.server.URL, "admin", fakePassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, err := session.Check(); err != nil || status != SessionValid {
+		t.Fatalf("Check() = %v, %v, want %v", status, err, SessionValid)
+	}
+
+	if err := session.Logout(); err != nil {
+		t.Fatal(err)
+	}
+	if session.SID != invalidSID {
+		t.Errorf("SID = %q after Logout, want %q", session.SID, invalidSID)
+	}
+	if status, err := session.Check(); err != nil || status != SessionExpired {
+		t.Errorf("Check() = %v, %v after Logout, want %v", status, err, SessionExpired)
+	}
+
+	session.SID = box.currentSID()
+	box.expire()
+	if session.Valid() {
+		t.Error("Valid() = true for a SID that the Fritz!Box dropped")
+	}
+}
@@ -12,35 +12,50 @@
 package GoFritzBox
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-
-	"github.com/valyala/fasthttp"
 )
 
 // GetLogs returns Logs of the Fritz!Box activity
 func (s *SessionInfo) GetLogs() (Logs, error) {
-	request := fasthttp.AcquireRequest()
-	response := fasthttp.AcquireResponse()
-	defer func() {
-		fasthttp.ReleaseRequest(request)
-		fasthttp.ReleaseResponse(response)
-	}()
-
-	request.SetRequestURI(fmt.Sprintf("%s/data.lua", s.EndPoint))
-	request.SetBodyString(fmt.Sprintf("sid=%s&page=log&lang=%s&xhr=1&xhrId=all", s.SID, s.Lang))
-	request.Header.SetContentType("application/x-www-form-urlencoded")
-	request.Header.SetMethod(fasthttp.MethodPost)
+	return s.GetLogsContext(context.Background())
+}
 
-	err := client.Do(request, response)
+// GetLogsContext is like GetLogs, ctx bounds the request and cancels it when done
+func (s *SessionInfo) GetLogsContext(ctx context.Context) (Logs, error) {
+	response, err := s.do(ctx, readOnly, func(sid string) (*Request, error) {
+		return newPostRequest(
+			fmt.Sprintf("%s/data.lua", s.EndPoint),
+			"application/x-www-form-urlencoded",
+			[]byte(fmt.Sprintf("sid=%s&page=log&lang=%s&xhr=1&xhrId=all", sid, s.lang())),
+		), nil
+	})
 	if err != nil {
 		return Logs{}, err
 	}
 
 	var result RequestData
-	err = json.Unmarshal(response.Body(), &result)
+	err = decodeJSON(response, &result)
 	if err != nil {
 		return Logs{}, err
 	}
+	if result.Data.Log == nil {
+		return Logs{}, &ProtocolError{Endpoint: response.endpoint, Err: errors.New("no log in the answer")}
+	}
 	return *result.Data.Log, nil
 }
+
+// GetLogEntries returns the Logs of the Fritz!Box activity parsed into LogEntry, see Logs.Entries
+func (s *SessionInfo) GetLogEntries() (LogEntries, error) {
+	return s.GetLogEntriesContext(context.Background())
+}
+
+// GetLogEntriesContext is like GetLogEntries, ctx bounds the request and cancels it when done
+func (s *SessionInfo) GetLogEntriesContext(ctx context.Context) (LogEntries, error) {
+	logs, err := s.GetLogsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return logs.Entries(s.lang()), nil
+}
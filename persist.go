@@ -0,0 +1,136 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * persist.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// savedSession is the part of SessionInfo written by Save
+type savedSession struct {
+	SID      string    `json:"sid"`
+	EndPoint string    `json:"endpoint"`
+	Lang     string    `json:"lang"`
+	Rights   Rights    `json:"rights"`
+	Created  time.Time `json:"created"`
+}
+
+// Save writes the session to path, readable only by the current user
+// The file contains the SID, anyone that can read it can use the session
+func (s *SessionInfo) Save(path string) error {
+	data, err := json.Marshal(savedSession{
+		SID:      s.sid(),
+		EndPoint: s.EndPoint,
+		Lang:     s.Lang,
+		Rights:   s.Rights,
+		Created:  s.Created,
+	})
+	if err != nil {
+		return err
+	}
+	return writePrivateFile(path, data)
+}
+
+// writePrivateFile replaces the file at path with data, the file is readable only by the current user
+func writePrivateFile(path string, data []byte) error {
+	// TempFile creates the file with 0600 permissions, renaming it replaces the old file atomically
+	file, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(data)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+// LoadSession reads a session written by Save, without checking if it's still valid
+func LoadSession(path string) (SessionInfo, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return SessionInfo{}, err
+	}
+
+	var saved savedSession
+	err = json.Unmarshal(data, &saved)
+	if err != nil {
+		return SessionInfo{}, err
+	}
+
+	return SessionInfo{
+		SID:      saved.SID,
+		EndPoint: saved.EndPoint,
+		Lang:     saved.Lang,
+		Rights:   saved.Rights,
+		Created:  saved.Created,
+	}, nil
+}
+
+// Resume returns the session saved in path if the Fritz!Box at endpoint still accepts its SID
+// Otherwise it does a login with the Credentials and saves the new session in path
+// The returned session keeps the Credentials, like the ones created by LoginWithCredentials
+func Resume(path, endpoint string, credentials Credentials) (SessionInfo, error) {
+	return DefaultClient.Resume(path, endpoint, credentials)
+}
+
+// ResumeContext is like Resume, ctx bounds the requests and cancels them when done
+func ResumeContext(ctx context.Context, path, endpoint string, credentials Credentials) (SessionInfo, error) {
+	return DefaultClient.ResumeContext(ctx, path, endpoint, credentials)
+}
+
+// Resume restores or creates a session like the Resume function, the session uses the Client for its requests
+func (c *Client) Resume(path, endpoint string, credentials Credentials) (SessionInfo, error) {
+	return c.ResumeContext(context.Background(), path, endpoint, credentials)
+}
+
+// ResumeContext is like Resume, ctx bounds the requests and cancels them when done
+func (c *Client) ResumeContext(ctx context.Context, path, endpoint string, credentials Credentials) (SessionInfo, error) {
+	normalized, err := NormalizeEndpoint(endpoint)
+	if err != nil {
+		return SessionInfo{}, err
+	}
+
+	session, err := LoadSession(path)
+	session.Client = c
+	if err == nil && session.EndPoint == normalized {
+		status, err := session.CheckContext(ctx)
+		if err != nil {
+			return SessionInfo{}, err
+		}
+		if status == SessionValid {
+			session.credentials = credentials
+			return session, nil
+		}
+	} else if err != nil && !os.IsNotExist(err) {
+		return SessionInfo{}, err
+	}
+
+	session, err = c.LoginWithCredentialsContext(ctx, endpoint, credentials)
+	if err != nil {
+		return session, err
+	}
+	return session, session.Save(path)
+}
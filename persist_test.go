@@ -0,0 +1,82 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * persist_test.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestSaveAndLoadSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	session := SessionInfo{
+		SID:      "0123456789abcdef",
+		EndPoint: "http://fritz.box",
+		Lang:     "en",
+		Rights:   Rights{RightBoxAdmin: AccessWrite},
+		Created:  time.Date(2021, 3, 7, 12, 0, 0, 0, time.UTC),
+	}
+	if err := session.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	loaded, err := LoadSession(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.SID != session.SID || loaded.EndPoint != session.EndPoint || loaded.Lang != session.Lang ||
+		!loaded.Rights.CanWrite(RightBoxAdmin) || !loaded.Created.Equal(session.Created) {
+		t.Errorf("LoadSession() = %+v, want %+v", loaded, session)
+	}
+}
+
+func TestResume(t *testing.T) {
+	box := newFakeBox(t)
+	path := filepath.Join(t.TempDir(), "session.json")
+	credentials := StaticCredentials("admin", fakePassword)
+
+	first, err := Resume(path, box.server.URL, credentials)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Resume(path, box.server.URL, credentials)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.SID != first.SID || box.loginCount() != 1 {
+		t.Errorf("resumed SID %q after %d logins, want %q after 1 login", second.SID, box.loginCount(), first.SID)
+	}
+
+	box.expire()
+	third, err := Resume(path, box.server.URL, credentials)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := LoadSession(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.SID == first.SID || saved.SID != third.SID || box.loginCount() != 2 {
+		t.Errorf("SID %q saved as %q after %d logins, want a new SID after 2 logins", third.SID, saved.SID, box.loginCount())
+	}
+}
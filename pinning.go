@@ -0,0 +1,191 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * pinning.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// PinStore keeps the certificate fingerprints pinned for every Fritz!Box host
+// Get returns an empty fingerprint when the host has no pin yet
+// Set pins a fingerprint, replacing the old one, it's used to import or rotate pins as well
+type PinStore interface {
+	Get(host string) (string, error)
+	Set(host, fingerprint string) error
+	Delete(host string) error
+}
+
+// PinningTLSConfig returns a tls.Config that trusts the certificate of host on first use:
+// the fingerprint seen on the first connection is saved in store, and connections presenting
+// a different certificate fail with a CertificateMismatchError
+// When host is empty the TLS server name is used, which is empty for IP addresses: the connections
+// to an IP address fail unless host is set. Use a Client with its own TLSConfig for each Fritz!Box
+func PinningTLSConfig(store PinStore, host string) *tls.Config {
+	return &tls.Config{
+		// The Fritz!Box certificate is self-signed, the pin replaces the chain verification
+		InsecureSkipVerify: true,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return errors.New("the Fritz!Box didn't send a certificate")
+			}
+
+			key := host
+			if key == "" {
+				key = state.ServerName
+			}
+			if key == "" {
+				// Every IP address would share the same pin
+				return errors.New("no host to pin the certificate of, set the host of PinningTLSConfig for IP addresses")
+			}
+
+			fingerprint := CertificateFingerprint(state.PeerCertificates[0])
+			pinned, err := store.Get(key)
+			if err != nil {
+				return err
+			}
+
+			if pinned == "" {
+				return store.Set(key, fingerprint)
+			} else if pinned != fingerprint {
+				return &CertificateMismatchError{Host: key, Pinned: pinned, Received: fingerprint}
+			}
+			return nil
+		},
+	}
+}
+
+// CertificateFingerprint returns the hex encoded SHA256 hash of the certificate, the format used by PinStore
+func CertificateFingerprint(certificate *x509.Certificate) string {
+	hash := sha256.Sum256(certificate.Raw)
+	return hex.EncodeToString(hash[:])
+}
+
+// FetchFingerprint connects to address (host:port) and returns the fingerprint of its certificate without verifying it
+// It's useful to show the fingerprint to the user before importing or rotating a pin
+func FetchFingerprint(ctx context.Context, address string) (string, error) {
+	dialer := &tls.Dialer{Config: &tls.Config{InsecureSkipVerify: true}}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	certificates := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certificates) == 0 {
+		return "", errors.New("the Fritz!Box didn't send a certificate")
+	}
+	return CertificateFingerprint(certificates[0]), nil
+}
+
+// MemoryPinStore is a PinStore kept in memory, the zero value is ready to use
+type MemoryPinStore struct {
+	mutex sync.Mutex
+	pins  map[string]string
+}
+
+func (m *MemoryPinStore) Get(host string) (string, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.pins[host], nil
+}
+
+func (m *MemoryPinStore) Set(host, fingerprint string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.pins == nil {
+		m.pins = map[string]string{}
+	}
+	m.pins[host] = fingerprint
+	return nil
+}
+
+func (m *MemoryPinStore) Delete(host string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.pins, host)
+	return nil
+}
+
+// FilePinStore is a PinStore saved as a JSON object in the file at Path, readable only by the current user
+// The file is created on the first pin
+type FilePinStore struct {
+	Path  string
+	mutex sync.Mutex
+}
+
+func (f *FilePinStore) Get(host string) (string, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	pins, err := f.read()
+	if err != nil {
+		return "", err
+	}
+	return pins[host], nil
+}
+
+func (f *FilePinStore) Set(host, fingerprint string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	pins, err := f.read()
+	if err != nil {
+		return err
+	}
+	pins[host] = fingerprint
+	return f.write(pins)
+}
+
+func (f *FilePinStore) Delete(host string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	pins, err := f.read()
+	if err != nil {
+		return err
+	}
+	delete(pins, host)
+	return f.write(pins)
+}
+
+func (f *FilePinStore) read() (map[string]string, error) {
+	pins := map[string]string{}
+	data, err := ioutil.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		return pins, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &pins)
+	if err != nil {
+		return nil, err
+	}
+	return pins, nil
+}
+
+func (f *FilePinStore) write(pins map[string]string) error {
+	data, err := json.MarshalIndent(pins, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writePrivateFile(f.Path, data)
+}
@@ -0,0 +1,156 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * pinning_test.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newPinnedBox starts a fakeBox over TLS with a new self-signed certificate
+func newPinnedBox(t *testing.T) (*httptest.Server, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "fritz.box"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"fritz.box"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	box := &fakeBox{challenge: fakeChallenge}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(box.handle))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server, CertificateFingerprint(certificate)
+}
+
+func TestPinningTLSConfig(t *testing.T) {
+	server, fingerprint := newPinnedBox(t)
+	store := &MemoryPinStore{}
+
+	client := &Client{TLSConfig: PinningTLSConfig(store, "fritz.box")}
+	if _, _, err := client.ListUsers(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if pinned, _ := store.Get("fritz.box"); pinned != fingerprint {
+		t.Errorf("pinned %q on first use, want %q", pinned, fingerprint)
+	}
+
+	other, otherFingerprint := newPinnedBox(t)
+	client = &Client{TLSConfig: PinningTLSConfig(store, "fritz.box")}
+	_, _, err := client.ListUsers(other.URL)
+	var mismatch *CertificateMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("err = %v, want a CertificateMismatchError", err)
+	}
+	if mismatch.Host != "fritz.box" || mismatch.Pinned != fingerprint || mismatch.Received != otherFingerprint {
+		t.Errorf("mismatch = %+v, want fritz.box pinned to %s, received %s", mismatch, fingerprint, otherFingerprint)
+	}
+
+	// Rotating the pin with Set accepts the new certificate
+	if err := store.Set("fritz.box", otherFingerprint); err != nil {
+		t.Fatal(err)
+	}
+	client = &Client{TLSConfig: PinningTLSConfig(store, "fritz.box")}
+	if _, _, err := client.ListUsers(other.URL); err != nil {
+		t.Errorf("rotated pin: %v", err)
+	}
+
+	// After Delete the next certificate is trusted on first use again
+	if err := store.Delete("fritz.box"); err != nil {
+		t.Fatal(err)
+	}
+	client = &Client{TLSConfig: PinningTLSConfig(store, "fritz.box")}
+	if _, _, err := client.ListUsers(server.URL); err != nil {
+		t.Errorf("deleted pin: %v", err)
+	}
+	if pinned, _ := store.Get("fritz.box"); pinned != fingerprint {
+		t.Errorf("pinned %q after Delete, want %q", pinned, fingerprint)
+	}
+}
+
+func TestFilePinStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pins.json")
+	store := &FilePinStore{Path: path}
+
+	if pinned, err := store.Get("fritz.box"); err != nil || pinned != "" {
+		t.Fatalf("Get on a missing file = %q, %v, want no pin", pinned, err)
+	}
+	if err := store.Set("fritz.box", "abcd"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("192.168.178.1", "ef01"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete("192.168.178.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := &FilePinStore{Path: path}
+	if pinned, err := reopened.Get("fritz.box"); err != nil || pinned != "abcd" {
+		t.Errorf("Get = %q, %v, want abcd", pinned, err)
+	}
+	if pinned, err := reopened.Get("192.168.178.1"); err != nil || pinned != "" {
+		t.Errorf("Get of a deleted pin = %q, %v, want no pin", pinned, err)
+	}
+}
+
+func TestFetchFingerprint(t *testing.T) {
+	server, fingerprint := newPinnedBox(t)
+
+	got, err := FetchFingerprint(context.Background(), server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != fingerprint {
+		t.Errorf("FetchFingerprint = %q, want %q", got, fingerprint)
+	}
+}
+
+func TestPinningTLSConfigWithoutHost(t *testing.T) {
+	server, _ := newPinnedBox(t)
+	store := &MemoryPinStore{}
+
+	// The server is at 127.0.0.1, there's no server name to pin the certificate with
+	client := &Client{TLSConfig: PinningTLSConfig(store, "")}
+	if _, _, err := client.ListUsers(server.URL); err == nil {
+		t.Error("ListUsers pinned the certificate of an IP address without host")
+	}
+	if pinned, _ := store.Get(""); pinned != "" {
+		t.Errorf("pinned %q under an empty host", pinned)
+	}
+}
@@ -12,12 +12,26 @@
 package GoFritzBox
 
 import (
+	"crypto/hmac"
 	"crypto/md5"
+	"crypto/sha256"
 	"encoding/binary"
 	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
 	"unicode/utf16"
 )
 
+// prepareResponse builds the login response for the challenge returned by login_sid.lua
+// Challenges starting with "2$" use PBKDF2 (Fritz!OS 7.24+), all the others use the legacy MD5 scheme
+func prepareResponse(challenge, password string) (string, error) {
+	if strings.HasPrefix(challenge, "2$") {
+		return preparePBKDF2Password(challenge, password)
+	}
+	return challenge + "-" + preparePassword(challenge, password), nil
+}
+
 // preparePassword hashes with MD5 the UTF16LE conversion of the parameters
 func preparePassword(challenge, password string) string {
 	converted := utf16.Encode([]rune(challenge + "-" + password))
@@ -31,3 +45,54 @@ func preparePassword(challenge, password string) string {
 	hash := hasher.Sum(nil)
 	return hex.EncodeToString(hash)
 }
+
+// preparePBKDF2Password solves a challenge in the format 2$<iter1>$<salt1>$<iter2>$<salt2>
+// The password is hashed with salt1, the result is hashed again with salt2,
+// the response is <salt2>$<hash2>
+func preparePBKDF2Password(challenge, password string) (string, error) {
+	parts := strings.Split(challenge, "$")
+	if len(parts) != 5 {
+		return "", errors.New("invalid PBKDF2 challenge")
+	}
+
+	iter1, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return "", err
+	}
+	salt1, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return "", err
+	}
+	iter2, err := strconv.Atoi(parts[3])
+	if err != nil {
+		return "", err
+	}
+	salt2, err := hex.DecodeString(parts[4])
+	if err != nil {
+		return "", err
+	}
+
+	hash1 := pbkdf2SHA256([]byte(password), salt1, iter1)
+	hash2 := pbkdf2SHA256(hash1, salt2, iter2)
+	return parts[4] + "$" + hex.EncodeToString(hash2), nil
+}
+
+// pbkdf2SHA256 derives a single SHA256 sized block, which is all the Fritz!Box needs
+func pbkdf2SHA256(password, salt []byte, iterations int) []byte {
+	mac := hmac.New(sha256.New, password)
+	mac.Write(salt)
+	mac.Write([]byte{0, 0, 0, 1})
+	u := mac.Sum(nil)
+
+	result := make([]byte, len(u))
+	copy(result, u)
+	for i := 1; i < iterations; i++ {
+		mac.Reset()
+		mac.Write(u)
+		u = mac.Sum(u[:0])
+		for j := range result {
+			result[j] ^= u[j]
+		}
+	}
+	return result
+}
@@ -0,0 +1,40 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * prepare_password_test.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import "testing"
+
+// The examples of the AVM technical note about the session ID
+func TestPrepareResponse(t *testing.T) {
+	tests := []struct {
+		challenge, password, want string
+	}{
+		{"1234567z", "äbc", "1234567z-9e224a41eeefa284df7bb0f26c2913e2"},
+		{"2$10000$5A1711$2000$5A1722", "1example!", "5A1722$1798a1672bca7c6463d6b245f82b53703b0f50813401b03e4045a5861e689adb"},
+	}
+	for _, test := range tests {
+		got, err := prepareResponse(test.challenge, test.password)
+		if err != nil {
+			t.Errorf("prepareResponse(%q) failed: %v", test.challenge, err)
+		} else if got != test.want {
+			t.Errorf("prepareResponse(%q) = %q, want %q", test.challenge, got, test.want)
+		}
+	}
+}
+
+func TestPrepareResponseBadChallenge(t *testing.T) {
+	for _, challenge := range []string{"2$10000$5A1711", "2$x$5A1711$2000$5A1722", "2$10000$zz$2000$5A1722"} {
+		if _, err := prepareResponse(challenge, "1example!"); err == nil {
+			t.Errorf("prepareResponse(%q) succeeded, want an error", challenge)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * request.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// lang returns the lang of the session, DefaultLang if it's not set
+func (s *SessionInfo) lang() string {
+	if s.Lang == "" {
+		return DefaultLang
+	}
+	return s.Lang
+}
+
+// client returns the Client of the session, DefaultClient if it's not set
+func (s *SessionInfo) client() *Client {
+	if s.Client == nil {
+		return DefaultClient
+	}
+	return s.Client
+}
+
+// getSessionInfo does a GET request to login_sid.lua and decodes its answer
+func (c *Client) getSessionInfo(ctx context.Context, kind callKind, uri string) (SessionInfo, error) {
+	response, err := c.do(ctx, kind, newGetRequest(uri))
+	if err != nil {
+		return SessionInfo{}, err
+	}
+	if !isSuccess(response) {
+		return SessionInfo{}, newUnexpectedResponseError(response)
+	}
+
+	var result SessionInfo
+	err = xml.Unmarshal(response.Body, &result)
+	if err != nil {
+		return SessionInfo{}, &ProtocolError{Endpoint: response.endpoint, Err: err}
+	}
+	return result, nil
+}
+
+// sessionGuard protects the SID of a session shared by several goroutines
+// mu guards the fields written by relogin, renew lets a single goroutine replace a rejected SID
+type sessionGuard struct {
+	mu    sync.Mutex
+	renew sync.Mutex
+}
+
+// guardsMutex protects the creation of the sessionGuard of every session
+var guardsMutex sync.Mutex
+
+// lock returns the sessionGuard of the session, creating it on first use
+// so that sessions built as literals get their own one as well
+func (s *SessionInfo) lock() *sessionGuard {
+	guardsMutex.Lock()
+	defer guardsMutex.Unlock()
+	if s.guard == nil {
+		s.guard = &sessionGuard{}
+	}
+	return s.guard
+}
+
+// sid returns the current SID of the session
+func (s *SessionInfo) sid() string {
+	guard := s.lock()
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+	return s.SID
+}
+
+// setSID replaces the SID of the session
+func (s *SessionInfo) setSID(sid string) {
+	guard := s.lock()
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+	s.SID = sid
+}
+
+// do sends the Request returned by build and returns the successful response
+// build receives the SID to send, it's called again with the new SID
+// when the session logs in again after the Fritz!Box rejected the old one
+// ErrSessionExpired is returned when the SID is not valid anymore and the session can't login again,
+// an UnexpectedResponseError when the Fritz!Box answers with an error status
+func (s *SessionInfo) do(ctx context.Context, kind callKind, build func(sid string) (*Request, error)) (*Response, error) {
+	sid := s.sid()
+	response, err := s.send(ctx, kind, sid, build)
+	if err != nil {
+		return nil, err
+	}
+
+	if isRejected(response) {
+		sid, renewed, err := s.renew(ctx, sid)
+		if err != nil {
+			return nil, err
+		}
+
+		if renewed {
+			response, err = s.send(ctx, kind, sid, build)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if !isSuccess(response) {
+		return nil, newUnexpectedResponseError(response)
+	}
+	return response, nil
+}
+
+// renew replaces the rejected SID, logging in again only if another goroutine didn't already do it
+// It returns the SID to retry with, renewed is false when the Fritz!Box still accepts the rejected SID
+// OnRelogin is called after the lock is released, so it can use the session
+func (s *SessionInfo) renew(ctx context.Context, rejected string) (string, bool, error) {
+	sid, renewed, relogged, err := s.renewSID(ctx, rejected)
+	if relogged && s.OnRelogin != nil {
+		s.OnRelogin(s)
+		// The requests of OnRelogin can replace the SID once more
+		sid = s.sid()
+	}
+	return sid, renewed, err
+}
+
+// renewSID does the work of renew holding its lock, relogged is true when it logged in again
+func (s *SessionInfo) renewSID(ctx context.Context, rejected string) (sid string, renewed, relogged bool, err error) {
+	guard := s.lock()
+	guard.renew.Lock()
+	defer guard.renew.Unlock()
+
+	if current := s.sid(); current != rejected {
+		return current, true, false, nil
+	}
+
+	// The answer could be unexpected for other reasons, ask the Fritz!Box if the SID is still valid
+	status, err := s.checkSID(ctx, rejected)
+	if err != nil {
+		return "", false, false, err
+	}
+	if status != SessionExpired {
+		return rejected, false, false, nil
+	}
+
+	if s.credentials == nil {
+		return "", false, false, ErrSessionExpired
+	}
+	err = s.relogin(ctx)
+	if err != nil {
+		return "", false, false, err
+	}
+	return s.sid(), true, true, nil
+}
+
+// send builds and sends a single request with sid
+func (s *SessionInfo) send(ctx context.Context, kind callKind, sid string, build func(sid string) (*Request, error)) (*Response, error) {
+	request, err := build(sid)
+	if err != nil {
+		return nil, err
+	}
+	return s.client().do(ctx, kind, request)
+}
+
+// isRejected returns true for the answers given instead of the data when the SID is not valid:
+// a forbidden status, a redirect, or the HTML of the login page
+func isRejected(response *Response) bool {
+	status := response.StatusCode
+	if status == http.StatusForbidden || (status >= 300 && status < 400) {
+		return true
+	}
+	return strings.HasPrefix(response.Header.Get("Content-Type"), "text/html")
+}
+
+// isSuccess returns true for the 2xx status codes
+func isSuccess(response *Response) bool {
+	return response.StatusCode >= 200 && response.StatusCode < 300
+}
+
+// decodeJSON decodes the JSON body of response in v
+// Bodies that are not JSON at all, like HTML pages, return an UnexpectedResponseError,
+// JSON that doesn't match v returns a ProtocolError
+func decodeJSON(response *Response, v interface{}) error {
+	err := json.Unmarshal(response.Body, v)
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) || (err != nil && len(bytes.TrimSpace(response.Body)) == 0) {
+		return newUnexpectedResponseError(response)
+	} else if err != nil {
+		return &ProtocolError{Endpoint: response.endpoint, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * request_test.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSessionLang(t *testing.T) {
+	box := newFakeBox(t)
+
+	session, err := Login(box.server.URL, "admin", fakePassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.Lang != DefaultLang {
+		t.Errorf("Lang = %q after Login, want %q", session.Lang, DefaultLang)
+	}
+
+	data, err := session.LoadInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Language != DefaultLang {
+		t.Errorf("LoadInfo used lang %q, want %q", data.Language, DefaultLang)
+	}
+
+	session.Lang = "en"
+	if _, err := session.LoadInfo(); err != nil {
+		t.Fatal(err)
+	}
+	if lang := box.lastLang(); lang != "en" {
+		t.Errorf("LoadInfo used lang %q, want en", lang)
+	}
+	if _, err := session.GetLogs(); err != nil {
+		t.Fatal(err)
+	}
+	if lang := box.lastLang(); lang != "en" {
+		t.Errorf("GetLogs used lang %q, want en", lang)
+	}
+
+	session.Lang = ""
+	if _, err := session.GetLogs(); err != nil {
+		t.Fatal(err)
+	}
+	if lang := box.lastLang(); lang != DefaultLang {
+		t.Errorf("GetLogs used lang %q without Lang, want %q", lang, DefaultLang)
+	}
+}
+
+func TestIsRejected(t *testing.T) {
+	tests := []struct {
+		status      int
+		contentType string
+		want        bool
+	}{
+		{http.StatusOK, "application/json", false},
+		{http.StatusOK, "text/html; charset=utf-8", true},
+		{http.StatusFound, "", true},
+		{http.StatusForbidden, "text/plain", true},
+		{http.StatusInternalServerError, "text/plain", false},
+	}
+	for _, test := range tests {
+		response := &Response{StatusCode: test.status, Header: http.Header{"Content-Type": {test.contentType}}}
+		if got := isRejected(response); got != test.want {
+			t.Errorf("isRejected(%d, %q) = %v, want %v", test.status, test.contentType, got, test.want)
+		}
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	var result RequestData
+	response := &Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: []byte(`{"data":{"log":[]}}`)}
+	if err := decodeJSON(response, &result); err != nil {
+		t.Error(err)
+	}
+
+	page := "<html>" + strings.Repeat("x", 1000) + "</html>"
+	for _, body := range []string{page, "", "  "} {
+		response := &Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"text/html"}},
+			Body:       []byte(body),
+		}
+		err := decodeJSON(response, &result)
+		var unexpected *UnexpectedResponseError
+		if !errors.As(err, &unexpected) || !errors.Is(err, ErrUnexpectedResponse) {
+			t.Errorf("decodeJSON(%.10q) = %v, want an UnexpectedResponseError", body, err)
+			continue
+		}
+		if unexpected.ContentType != "text/html" || len(unexpected.Snippet) > snippetLength ||
+			!strings.HasPrefix(body, unexpected.Snippet) {
+			t.Errorf("UnexpectedResponseError = %+v, want the text/html type and the first %d bytes", unexpected, snippetLength)
+		}
+	}
+
+	// Valid JSON of another shape is a decoding error, not an unexpected response
+	response = &Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: []byte(`{"data":[]}`)}
+	if err := decodeJSON(response, &result); err == nil || errors.Is(err, ErrUnexpectedResponse) {
+		t.Errorf("err = %v, want a JSON type error", err)
+	}
+}
+
+func TestUnexpectedStatus(t *testing.T) {
+	session, _ := newFlakySession(t, 1, nil)
+
+	_, err := session.GetLogs()
+	var unexpected *UnexpectedResponseError
+	if !errors.As(err, &unexpected) || unexpected.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("err = %v, want an UnexpectedResponseError with status 503", err)
+	}
+}
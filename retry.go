@@ -0,0 +1,107 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * retry.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// callKind tells if a request can be sent again without side effects
+type callKind int
+
+const (
+	// readOnly requests, like GetStats or LoadInfo, are always safe to retry
+	readOnly callKind = iota
+	// changesState requests, like Disconnect or a login attempt, are retried only when RetryStateChanging is set
+	changesState
+)
+
+// DefaultRetryPolicy is a RetryPolicy good enough for polling a Fritz!Box on Wi-Fi
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	Backoff:    500 * time.Millisecond,
+	MaxBackoff: 5 * time.Second,
+}
+
+// RetryPolicy configures how a Client retries requests that failed for transient errors:
+// connection errors and the 502, 503 and 504 status codes the Fritz!Box returns when it's busy
+// MaxRetries is the number of retries after the first attempt
+// Backoff is the wait before the first retry, it doubles on every retry up to MaxBackoff
+// RetryStateChanging allows to retry the requests that change the Fritz!Box state, like Disconnect:
+// a request that failed while reading the response could be applied twice
+type RetryPolicy struct {
+	MaxRetries         int
+	Backoff            time.Duration
+	MaxBackoff         time.Duration
+	RetryStateChanging bool
+}
+
+// allows returns true if a request of the given kind can be retried after the failed attempt
+func (p *RetryPolicy) allows(kind callKind, attempt int) bool {
+	if p == nil || attempt >= p.MaxRetries {
+		return false
+	}
+	return kind == readOnly || p.RetryStateChanging
+}
+
+// backoff returns the wait before the retry that follows the failed attempt
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	wait := p.Backoff
+	for i := 0; i < attempt; i++ {
+		wait *= 2
+		if p.MaxBackoff > 0 && wait >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	return wait
+}
+
+// isTransient returns true if the request failed for a reason that can go away by itself
+func isTransient(response *Response, err error) bool {
+	if err == nil {
+		switch response.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		default:
+			return false
+		}
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	// net/http wraps every error in a url.Error, which looks like a net.Error
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, fasthttp.ErrConnectionClosed) ||
+		errors.Is(err, fasthttp.ErrTimeout) ||
+		errors.Is(err, fasthttp.ErrNoFreeConns)
+}
@@ -0,0 +1,194 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * retry_test.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyBox answers 503 to the first failures requests, then the log page or a successful disconnect
+type flakyBox struct {
+	mutex    sync.Mutex
+	failures int
+	requests int
+}
+
+func (b *flakyBox) handle(w http.ResponseWriter, r *http.Request) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.requests++
+	if b.failures > 0 {
+		b.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	switch r.URL.Path {
+	case "/data.lua":
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":{"log":[["18.10.21","10:00:00","DSL ist verfügbar","23","2","help"]]}}`)
+	default:
+		fmt.Fprint(w, "done:0")
+	}
+}
+
+func (b *flakyBox) requestCount() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.requests
+}
+
+func newFlakySession(t *testing.T, failures int, policy *RetryPolicy) (*SessionInfo, *flakyBox) {
+	box := &flakyBox{failures: failures}
+	server := httptest.NewServer(http.HandlerFunc(box.handle))
+	t.Cleanup(server.Close)
+	return &SessionInfo{
+		SID:      "0123456789abcdef",
+		EndPoint: server.URL,
+		Client:   &Client{Retry: policy},
+	}, box
+}
+
+func TestRetryReadOnly(t *testing.T) {
+	session, box := newFlakySession(t, 2, &RetryPolicy{MaxRetries: 3, Backoff: time.Millisecond})
+
+	logs, err := session.GetLogs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 1 {
+		t.Errorf("len(logs) = %d, want 1", len(logs))
+	}
+	if box.requestCount() != 3 {
+		t.Errorf("%d requests, want 3", box.requestCount())
+	}
+
+	session, box = newFlakySession(t, 5, &RetryPolicy{MaxRetries: 2, Backoff: time.Millisecond})
+	if _, err := session.GetLogs(); err == nil {
+		t.Error("GetLogs succeeded after running out of retries")
+	}
+	if box.requestCount() != 3 {
+		t.Errorf("%d requests, want 3", box.requestCount())
+	}
+}
+
+func TestRetryChangesState(t *testing.T) {
+	session, box := newFlakySession(t, 1, &RetryPolicy{MaxRetries: 3, Backoff: time.Millisecond})
+	if err := session.Disconnect(); err == nil {
+		t.Error("Disconnect succeeded on a 503")
+	}
+	if box.requestCount() != 1 {
+		t.Errorf("%d requests, want 1", box.requestCount())
+	}
+
+	session, box = newFlakySession(t, 1, &RetryPolicy{MaxRetries: 3, Backoff: time.Millisecond, RetryStateChanging: true})
+	if err := session.Disconnect(); err != nil {
+		t.Error(err)
+	}
+	if box.requestCount() != 2 {
+		t.Errorf("%d requests, want 2", box.requestCount())
+	}
+}
+
+func TestRetryContext(t *testing.T) {
+	session, box := newFlakySession(t, 5, &RetryPolicy{MaxRetries: 3, Backoff: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := session.GetLogsContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if box.requestCount() != 1 {
+		t.Errorf("%d requests, want 1", box.requestCount())
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	policy := &RetryPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+	for attempt, wait := range want {
+		if got := policy.backoff(attempt); got != wait {
+			t.Errorf("backoff(%d) = %v, want %v", attempt, got, wait)
+		}
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		status int
+		err    error
+		want   bool
+	}{
+		{status: http.StatusOK, want: false},
+		{status: http.StatusForbidden, want: false},
+		{status: http.StatusBadGateway, want: true},
+		{status: http.StatusServiceUnavailable, want: true},
+		{status: http.StatusGatewayTimeout, want: true},
+		{err: io.EOF, want: true},
+		{err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, want: true},
+		{err: context.Canceled, want: false},
+		{err: context.DeadlineExceeded, want: false},
+		{err: errors.New("bad request"), want: false},
+	}
+	for _, test := range tests {
+		if got := isTransient(&Response{StatusCode: test.status}, test.err); got != test.want {
+			t.Errorf("isTransient(%d, %v) = %v, want %v", test.status, test.err, got, test.want)
+		}
+	}
+}
+
+// newDroppingSession returns a session whose Fritz!Box closes every connection without answering
+func newDroppingSession(t *testing.T, policy *RetryPolicy) (*SessionInfo, *int32) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	}))
+	t.Cleanup(server.Close)
+	return &SessionInfo{
+		SID:      "0123456789abcdef",
+		EndPoint: server.URL,
+		Client:   &Client{Retry: policy},
+	}, &requests
+}
+
+// fasthttp retries the idempotent requests on its own, RetryPolicy must be the only one deciding
+func TestRetryConnectionErrors(t *testing.T) {
+	session, requests := newDroppingSession(t, &RetryPolicy{MaxRetries: 2, Backoff: time.Millisecond})
+	if err := session.Disconnect(); err == nil {
+		t.Error("Disconnect succeeded on a closed connection")
+	}
+	if n := atomic.LoadInt32(requests); n != 1 {
+		t.Errorf("Disconnect sent %d times, want 1", n)
+	}
+
+	session, requests = newDroppingSession(t, &RetryPolicy{MaxRetries: 2, Backoff: time.Millisecond})
+	if _, err := session.GetLogs(); err == nil {
+		t.Error("GetLogs succeeded on a closed connection")
+	}
+	if n := atomic.LoadInt32(requests); n != 3 {
+		t.Errorf("GetLogs sent %d times, want 3", n)
+	}
+}
@@ -12,36 +12,36 @@
 package GoFritzBox
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"time"
-
-	"github.com/valyala/fasthttp"
 )
 
 // GetStats returns Stats to build the usage graph
 func (s *SessionInfo) GetStats() (Stats, error) {
-	request := fasthttp.AcquireRequest()
-	response := fasthttp.AcquireResponse()
-	defer func() {
-		fasthttp.ReleaseRequest(request)
-		fasthttp.ReleaseResponse(response)
-	}()
-
-	request.SetRequestURI(fmt.Sprintf(
-		"%s/internet/inetstat_monitor.lua?sid=%s&myXhr=1&action=get_graphic&useajax=1&xhr=1&t%d=nocache",
-		s.EndPoint, s.SID, time.Now().Unix(),
-	))
+	return s.GetStatsContext(context.Background())
+}
 
-	err := client.Do(request, response)
+// GetStatsContext is like GetStats, ctx bounds the request and cancels it when done
+func (s *SessionInfo) GetStatsContext(ctx context.Context) (Stats, error) {
+	response, err := s.do(ctx, readOnly, func(sid string) (*Request, error) {
+		return newGetRequest(fmt.Sprintf(
+			"%s/internet/inetstat_monitor.lua?sid=%s&myXhr=1&action=get_graphic&useajax=1&xhr=1&t%d=nocache",
+			s.EndPoint, sid, time.Now().Unix(),
+		)), nil
+	})
 	if err != nil {
 		return Stats{}, err
 	}
 
 	var result []Stats
-	err = json.Unmarshal(response.Body(), &result)
+	err = decodeJSON(response, &result)
 	if err != nil {
 		return Stats{}, err
 	}
+	if len(result) == 0 {
+		return Stats{}, &ProtocolError{Endpoint: response.endpoint, Err: errors.New("no stats in the answer")}
+	}
 	return result[0], nil
 }
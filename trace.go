@@ -0,0 +1,128 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * trace.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// redacted replaces the secrets in a TraceEvent
+const redacted = "REDACTED"
+
+// secretFields are the query and form fields that are never shown in a TraceEvent:
+// session IDs, passwords, challenge responses and TOTP codes
+var secretFields = map[string]bool{
+	"sid":            true,
+	"password":       true,
+	"response":       true,
+	"tfa_googleauth": true,
+}
+
+// TraceEvent describes a request sent to the Fritz!Box, SIDs, passwords and challenge responses are redacted,
+// in the URL and Body as well as in the URL of Err
+// Body is the form body of the request, other bodies are described by their size and type only
+// Attempt counts the retries of the same request, starting from 0
+// StatusCode and ResponseSize are zero when the request failed with Err
+type TraceEvent struct {
+	Method       string
+	URL          string
+	Body         string
+	Attempt      int
+	StatusCode   int
+	Latency      time.Duration
+	ResponseSize int
+	Err          error
+}
+
+// String returns the TraceEvent on a single line
+func (e TraceEvent) String() string {
+	result := fmt.Sprintf("%s %s", e.Method, e.URL)
+	if e.Body != "" {
+		result += " body=" + e.Body
+	}
+	if e.Attempt > 0 {
+		result += fmt.Sprintf(" attempt=%d", e.Attempt)
+	}
+	if e.Err != nil {
+		return result + fmt.Sprintf(" error=%q latency=%s", e.Err, e.Latency)
+	}
+	return result + fmt.Sprintf(" status=%d size=%d latency=%s", e.StatusCode, e.ResponseSize, e.Latency)
+}
+
+// LogTrace returns a Client Trace function that prints every TraceEvent with logger
+func LogTrace(logger *log.Logger) func(TraceEvent) {
+	return func(event TraceEvent) {
+		logger.Println(event)
+	}
+}
+
+// newTraceEvent returns the TraceEvent of a request, with its secrets redacted
+func newTraceEvent(request *Request, response *Response, err error, attempt int, latency time.Duration) TraceEvent {
+	event := TraceEvent{
+		Method:  request.Method,
+		URL:     redactURL(request.URL),
+		Attempt: attempt,
+		Latency: latency,
+		Err:     redactError(err),
+	}
+
+	if len(request.Body) > 0 {
+		contentType := request.Header.Get("Content-Type")
+		if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+			event.Body = redactForm(string(request.Body))
+		} else {
+			event.Body = fmt.Sprintf("[%d bytes of %s]", len(request.Body), contentType)
+		}
+	}
+
+	if response != nil {
+		event.StatusCode = response.StatusCode
+		event.ResponseSize = len(response.Body)
+	}
+	return event
+}
+
+// redactError replaces the secrets in the URL of the url.Error returned by net/http
+func redactError(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		return &url.Error{Op: urlErr.Op, URL: redactURL(urlErr.URL), Err: urlErr.Err}
+	}
+	return err
+}
+
+// redactURL replaces the secrets in the query of rawURL
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return redacted
+	}
+	u.RawQuery = redactForm(u.RawQuery)
+	return u.String()
+}
+
+// redactForm replaces the secrets in a form encoded string
+func redactForm(form string) string {
+	values, err := url.ParseQuery(form)
+	if err != nil {
+		return redacted
+	}
+	for k := range values {
+		if secretFields[strings.ToLower(k)] {
+			values[k] = []string{redacted}
+		}
+	}
+	return values.Encode()
+}
@@ -0,0 +1,125 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * trace_test.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRedactURL(t *testing.T) {
+	tests := map[string]string{
+		"http://fritz.box/login_sid.lua?version=2":                        "http://fritz.box/login_sid.lua?version=2",
+		"http://fritz.box/login_sid.lua?response=abc&username=admin":      "http://fritz.box/login_sid.lua?response=REDACTED&username=admin",
+		"http://fritz.box/internet/inetstat_monitor.lua?SID=0123&myXhr=1": "http://fritz.box/internet/inetstat_monitor.lua?SID=REDACTED&myXhr=1",
+	}
+	for rawURL, want := range tests {
+		if got := redactURL(rawURL); got != want {
+			t.Errorf("redactURL(%q) = %q, want %q", rawURL, got, want)
+		}
+	}
+}
+
+func TestRedactForm(t *testing.T) {
+	tests := map[string]string{
+		"sid=0123&page=log&lang=de":      "lang=de&page=log&sid=REDACTED",
+		"tfa_googleauth=123456&sid=0123": "sid=REDACTED&tfa_googleauth=REDACTED",
+		"username=admin&password=secret": "password=REDACTED&username=admin",
+		"%zz":                            redacted,
+	}
+	for form, want := range tests {
+		if got := redactForm(form); got != want {
+			t.Errorf("redactForm(%q) = %q, want %q", form, got, want)
+		}
+	}
+}
+
+func TestTrace(t *testing.T) {
+	box := newFakeBox(t)
+	var mutex sync.Mutex
+	var events []TraceEvent
+	client := &Client{Trace: func(event TraceEvent) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		events = append(events, event)
+	}}
+
+	session, err := client.Login(box.server.URL, "admin", fakePassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := session.GetLogs(); err != nil {
+		t.Fatal(err)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(events) != 3 {
+		t.Fatalf("%d events, want 3", len(events))
+	}
+	response, _ := prepareResponse(fakeChallenge, fakePassword)
+	for _, event := range events {
+		line := event.String()
+		for _, secret := range []string{session.SID, response, fakePassword} {
+			if strings.Contains(line, secret) {
+				t.Errorf("event %q contains the secret %q", line, secret)
+			}
+		}
+		if event.StatusCode != 200 || event.ResponseSize == 0 {
+			t.Errorf("event %q has no response", line)
+		}
+	}
+	if !strings.Contains(events[1].URL, "response="+redacted) {
+		t.Errorf("login URL = %q, want the response redacted", events[1].URL)
+	}
+	if events[2].Method != "POST" || !strings.Contains(events[2].Body, "sid="+redacted) {
+		t.Errorf("data event = %s %q, want a POST with the sid redacted", events[2].Method, events[2].Body)
+	}
+}
+
+func TestTraceError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	}))
+	defer server.Close()
+
+	var events []TraceEvent
+	session := &SessionInfo{
+		SID:      "0123456789abcdef",
+		EndPoint: server.URL,
+		Client: &Client{Transport: &HTTPTransport{}, Trace: func(event TraceEvent) {
+			events = append(events, event)
+		}},
+	}
+	if err := session.Disconnect(); err == nil {
+		t.Fatal("Disconnect succeeded on a closed connection")
+	}
+
+	if len(events) != 1 || events[0].Err == nil {
+		t.Fatalf("events = %v, want one failed request", events)
+	}
+	var urlErr *url.Error
+	if !errors.As(events[0].Err, &urlErr) || !strings.Contains(urlErr.URL, "sid="+redacted) {
+		t.Errorf("Err = %v, want a url.Error with the sid redacted", events[0].Err)
+	}
+	if line := events[0].String(); strings.Contains(line, session.SID) {
+		t.Errorf("event %q contains the SID", line)
+	}
+}
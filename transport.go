@@ -0,0 +1,77 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * transport.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"path"
+)
+
+// Transport sends the requests of a Client to the Fritz!Box
+// Implementations must honor the cancellation and the deadline of ctx
+type Transport interface {
+	Do(ctx context.Context, request *Request) (*Response, error)
+}
+
+// backgroundTransport is a Transport that can leave a request running after ctx made it return,
+// it calls finished when the request is really over, so the Limit slot isn't freed too early
+type backgroundTransport interface {
+	do(ctx context.Context, request *Request, finished func()) (*Response, error)
+}
+
+// Request is an HTTP request built by GoFritzBox
+type Request struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+}
+
+// Response is the HTTP response of the Fritz!Box, Body is read completely
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	endpoint   string
+}
+
+// endpointName returns the name of the Fritz!Box page requested by url, like data.lua
+func endpointName(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return path.Base(u.Path)
+}
+
+// newGetRequest returns a GET Request for url
+func newGetRequest(url string) *Request {
+	return &Request{
+		Method: http.MethodGet,
+		URL:    url,
+		Header: http.Header{},
+	}
+}
+
+// newPostRequest returns a POST Request for url with the given body
+func newPostRequest(url, contentType string, body []byte) *Request {
+	request := &Request{
+		Method: http.MethodPost,
+		URL:    url,
+		Header: http.Header{},
+		Body:   body,
+	}
+	request.Header.Set("Content-Type", contentType)
+	return request
+}
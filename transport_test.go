@@ -0,0 +1,92 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * transport_test.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// recordingTransport answers every request with body and records the requests it gets
+type recordingTransport struct {
+	body     string
+	requests []*Request
+}
+
+func (t *recordingTransport) Do(ctx context.Context, request *Request) (*Response, error) {
+	t.requests = append(t.requests, request)
+	return &Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       []byte(t.body),
+	}, nil
+}
+
+func TestHTTPTransport(t *testing.T) {
+	box := newFakeBox(t)
+	client := &Client{Transport: &HTTPTransport{}, UserAgent: "GoFritzBox-test"}
+
+	session, err := client.LoginWithCredentials(box.server.URL, StaticCredentials("admin", fakePassword))
+	if err != nil {
+		t.Fatal(err)
+	}
+	box.expire()
+
+	logs, err := session.GetLogs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 1 {
+		t.Errorf("len(logs) = %d, want 1", len(logs))
+	}
+	if box.loginCount() != 2 {
+		t.Errorf("%d logins, want 2", box.loginCount())
+	}
+	box.mutex.Lock()
+	defer box.mutex.Unlock()
+	if box.userAgent != "GoFritzBox-test" {
+		t.Errorf("User-Agent = %q, want %q", box.userAgent, "GoFritzBox-test")
+	}
+}
+
+func TestCustomTransport(t *testing.T) {
+	transport := &recordingTransport{body: `{"data":{"log":[["18.10.21","10:00:00","DSL ist verfügbar","23","2","help"]]}}`}
+	session := &SessionInfo{
+		SID:      "0123456789abcdef",
+		EndPoint: "http://fritz.box",
+		Client:   &Client{Transport: transport, UserAgent: "GoFritzBox-test"},
+	}
+
+	logs, err := session.GetLogs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 1 {
+		t.Errorf("len(logs) = %d, want 1", len(logs))
+	}
+
+	if len(transport.requests) != 1 {
+		t.Fatalf("%d requests, want 1", len(transport.requests))
+	}
+	request := transport.requests[0]
+	if request.Method != http.MethodPost || request.URL != "http://fritz.box/data.lua" {
+		t.Errorf("request = %s %s, want POST http://fritz.box/data.lua", request.Method, request.URL)
+	}
+	if !strings.Contains(string(request.Body), "sid=0123456789abcdef") {
+		t.Errorf("body = %q, want the sid", request.Body)
+	}
+	if got := request.Header.Get("User-Agent"); got != "GoFritzBox-test" {
+		t.Errorf("User-Agent = %q, want %q", got, "GoFritzBox-test")
+	}
+}
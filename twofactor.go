@@ -0,0 +1,203 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * twofactor.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// StartAction applies a setting by posting values to a data.lua page, like the web interface does
+// If the Fritz!Box asks for a second factor the action is suspended and a TFAChallenge is returned:
+// confirm it with SubmitTOTP or Wait, then call Complete to apply the action
+// Otherwise the TFAChallenge is nil and the answer of the Fritz!Box is returned
+func (s *SessionInfo) StartAction(page string, values url.Values) ([]byte, *TFAChallenge, error) {
+	return s.StartActionContext(context.Background(), page, values)
+}
+
+// StartActionContext is like StartAction, ctx bounds the request and cancels it when done
+func (s *SessionInfo) StartActionContext(ctx context.Context, page string, values url.Values) ([]byte, *TFAChallenge, error) {
+	response, err := s.postAction(ctx, page, values)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var result struct {
+		Data struct {
+			TwoFactor string `json:"twofactor"`
+		} `json:"data"`
+	}
+	// Answers of some pages are not JSON, they can't ask for a second factor
+	if json.Unmarshal(response.Body, &result) != nil || result.Data.TwoFactor == "" {
+		return response.Body, nil, nil
+	}
+
+	challenge := &TFAChallenge{session: s, page: page, values: values}
+	for _, method := range strings.Split(result.Data.TwoFactor, ",") {
+		parts := strings.SplitN(method, ";", 2)
+		challenge.Methods = append(challenge.Methods, TFAMethod(parts[0]))
+		if TFAMethod(parts[0]) == TFADect && len(parts) == 2 {
+			challenge.DectCode = parts[1]
+		}
+	}
+	return nil, challenge, nil
+}
+
+// Offers returns true if the Fritz!Box accepts the given TFAMethod for the action
+func (c *TFAChallenge) Offers(method TFAMethod) bool {
+	for _, v := range c.Methods {
+		if v == method {
+			return true
+		}
+	}
+	return false
+}
+
+// SubmitTOTP confirms the action with the code of the authenticator app, ErrTOTPRefused is returned for wrong codes
+func (c *TFAChallenge) SubmitTOTP(code string) error {
+	return c.SubmitTOTPContext(context.Background(), code)
+}
+
+// SubmitTOTPContext is like SubmitTOTP, ctx bounds the request and cancels it when done
+func (c *TFAChallenge) SubmitTOTPContext(ctx context.Context, code string) error {
+	if !c.Offers(TFATOTP) {
+		return &UnsupportedError{Feature: "TOTP confirmation of this action"}
+	}
+
+	response, err := c.twoFactor(ctx, changesState, url.Values{"tfa_googleauth": {code}})
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Err int `json:"err"`
+	}
+	err = decodeJSON(response, &result)
+	if err != nil {
+		return err
+	}
+
+	if result.Err == 0 {
+		return nil
+	} else {
+		return ErrTOTPRefused
+	}
+}
+
+// Status asks the Fritz!Box if the action has been confirmed
+// ErrTFANotPending is returned when the confirmation is not pending anymore, because it expired or was refused
+func (c *TFAChallenge) Status() (bool, error) {
+	return c.StatusContext(context.Background())
+}
+
+// StatusContext is like Status, ctx bounds the request and cancels it when done
+func (c *TFAChallenge) StatusContext(ctx context.Context) (bool, error) {
+	response, err := c.twoFactor(ctx, readOnly, url.Values{"tfa_active": {""}})
+	if err != nil {
+		return false, err
+	}
+
+	var result struct {
+		Active bool `json:"active"`
+		Done   bool `json:"done"`
+	}
+	err = decodeJSON(response, &result)
+	if err != nil {
+		return false, err
+	}
+
+	if !result.Active && !result.Done {
+		return false, ErrTFANotPending
+	}
+	return result.Done, nil
+}
+
+// Wait polls the Fritz!Box every interval until the action is confirmed,
+// by pressing a button on the Fritz!Box, dialing the DectCode or submitting a TOTP, or until ctx is done
+func (c *TFAChallenge) Wait(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		done, err := c.StatusContext(ctx)
+		if err != nil || done {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Complete applies the confirmed action and returns the answer of the Fritz!Box
+func (c *TFAChallenge) Complete() ([]byte, error) {
+	return c.CompleteContext(context.Background())
+}
+
+// CompleteContext is like Complete, ctx bounds the request and cancels it when done
+func (c *TFAChallenge) CompleteContext(ctx context.Context) ([]byte, error) {
+	values := url.Values{}
+	for k, v := range c.values {
+		values[k] = v
+	}
+	values.Set("twofactor", "")
+	values.Set("confirmed", "")
+
+	response, err := c.session.postAction(ctx, c.page, values)
+	if err != nil {
+		return nil, err
+	}
+	return response.Body, nil
+}
+
+// postAction posts values to a data.lua page
+func (s *SessionInfo) postAction(ctx context.Context, page string, values url.Values) (*Response, error) {
+	return s.do(ctx, changesState, func(sid string) (*Request, error) {
+		form := url.Values{}
+		for k, v := range values {
+			form[k] = v
+		}
+		form.Set("xhr", "1")
+		form.Set("sid", sid)
+		form.Set("lang", s.lang())
+		form.Set("page", page)
+
+		return newPostRequest(
+			fmt.Sprintf("%s/data.lua", s.EndPoint),
+			"application/x-www-form-urlencoded",
+			[]byte(form.Encode()),
+		), nil
+	})
+}
+
+// twoFactor posts values to twofactor.lua
+func (c *TFAChallenge) twoFactor(ctx context.Context, kind callKind, values url.Values) (*Response, error) {
+	s := c.session
+	return s.do(ctx, kind, func(sid string) (*Request, error) {
+		values.Set("xhr", "1")
+		values.Set("sid", sid)
+		values.Set("no_sidrenew", "")
+
+		return newPostRequest(
+			fmt.Sprintf("%s/twofactor.lua", s.EndPoint),
+			"application/x-www-form-urlencoded",
+			[]byte(values.Encode()),
+		), nil
+	})
+}
@@ -0,0 +1,144 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * twofactor_test.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTwoFactor is a Fritz!Box that asks for a second factor to save the "action" page
+// The action is confirmed by the TOTP 123456, or by a button press after pressAfter polls
+type fakeTwoFactor struct {
+	mutex      sync.Mutex
+	pressAfter int
+	polls      int
+	confirmed  bool
+	applied    bool
+}
+
+func (f *fakeTwoFactor) handle(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	_ = r.ParseForm()
+	_, confirmed := r.Form["confirmed"]
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.URL.Path == "/data.lua" && r.Form.Get("page") != "action":
+		fmt.Fprint(w, `{"data":{"saved":true}}`)
+	case r.URL.Path == "/data.lua" && confirmed && f.confirmed:
+		f.applied = true
+		fmt.Fprint(w, `{"data":{"applied":true}}`)
+	case r.URL.Path == "/data.lua":
+		fmt.Fprint(w, `{"data":{"twofactor":"button,dtmf;*1234,googleauth"}}`)
+	case r.Form.Get("tfa_googleauth") != "":
+		f.confirmed = r.Form.Get("tfa_googleauth") == "123456"
+		if f.confirmed {
+			fmt.Fprint(w, `{"err":0}`)
+		} else {
+			fmt.Fprint(w, `{"err":1}`)
+		}
+	default:
+		f.polls++
+		if f.pressAfter > 0 && f.polls >= f.pressAfter {
+			f.confirmed = true
+		}
+		fmt.Fprintf(w, `{"active":%t,"done":%t}`, !f.confirmed, f.confirmed)
+	}
+}
+
+func newTwoFactorSession(t *testing.T, fake *fakeTwoFactor) *SessionInfo {
+	server := httptest.NewServer(http.HandlerFunc(fake.handle))
+	t.Cleanup(server.Close)
+	return &SessionInfo{SID: "0123456789abcdef", EndPoint: server.URL, Lang: "en"}
+}
+
+func TestStartActionWithoutTwoFactor(t *testing.T) {
+	session := newTwoFactorSession(t, &fakeTwoFactor{})
+
+	body, challenge, err := session.StartAction("other", url.Values{"name": {"value"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if challenge != nil || !strings.Contains(string(body), "saved") {
+		t.Errorf("StartAction() = %s, %v, want the answer and no challenge", body, challenge)
+	}
+}
+
+func TestTwoFactorTOTP(t *testing.T) {
+	fake := &fakeTwoFactor{}
+	session := newTwoFactorSession(t, fake)
+
+	_, challenge, err := session.StartAction("action", url.Values{"name": {"value"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if challenge == nil {
+		t.Fatal("StartAction() returned no challenge")
+	}
+	if len(challenge.Methods) != 3 || !challenge.Offers(TFAButton) || !challenge.Offers(TFADect) ||
+		!challenge.Offers(TFATOTP) || challenge.DectCode != "*1234" {
+		t.Errorf("challenge = %+v", challenge)
+	}
+
+	if err := challenge.SubmitTOTP("000000"); !errors.Is(err, ErrTOTPRefused) {
+		t.Errorf("SubmitTOTP of a wrong code = %v, want ErrTOTPRefused", err)
+	}
+	if err := challenge.SubmitTOTP("123456"); err != nil {
+		t.Fatal(err)
+	}
+	if done, err := challenge.Status(); err != nil || !done {
+		t.Errorf("Status() = %v, %v, want true", done, err)
+	}
+
+	body, err := challenge.Complete()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), "applied") {
+		t.Errorf("Complete() = %s, want the applied action", body)
+	}
+}
+
+func TestTwoFactorWait(t *testing.T) {
+	fake := &fakeTwoFactor{pressAfter: 3}
+	session := newTwoFactorSession(t, fake)
+
+	_, challenge, err := session.StartAction("action", url.Values{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := challenge.Wait(ctx, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := challenge.Complete(); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if fake.polls != 3 || !fake.applied {
+		t.Errorf("%d polls, applied %v, want 3 polls and the applied action", fake.polls, fake.applied)
+	}
+}
@@ -12,7 +12,12 @@
 package GoFritzBox
 
 import (
+	"bytes"
 	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
@@ -26,13 +31,175 @@ import (
 // BlockTime is the cooldown needed after a wrong login, you need to handle it properly,
 // Lang is the lang that the Fritz!Box will use. Usually it's a useless parameters for API, but not on Fritz!OS
 // otherwise all the logins will fail, with the correct authentication as well
+// Login sets it to DefaultLang, change it once after Login and every request will use it
+// Rights are the permissions granted to the logged user
+// Users are the users configured on the Fritz!Box, as listed before the login
+// Created is the time of the login that generated the SID
+// Client is used for the requests of the session, DefaultClient when it's nil
+// OnRelogin is called after a session created by LoginWithCredentials logged in again because its SID expired,
+// the session can be used inside it
+// The requests of the session can run in different goroutines, SID must not be changed while they're running
 type SessionInfo struct {
-	SID       string      `xml:"SID"`
-	Challenge string      `xml:"Challenge"`
-	EndPoint  string      ``
-	BlockTime int         `xml:"BlockTime"`
-	Lang      string      ``
-	Rights    interface{} `` // Not Implemented
+	SID         string               `xml:"SID"`
+	Challenge   string               `xml:"Challenge"`
+	EndPoint    string               ``
+	BlockTime   int                  `xml:"BlockTime"`
+	Lang        string               ``
+	Rights      Rights               `xml:"Rights"`
+	Users       []User               `xml:"Users>User"`
+	Created     time.Time            `xml:"-"`
+	Client      *Client              `xml:"-"`
+	OnRelogin   func(s *SessionInfo) `xml:"-"`
+	credentials Credentials
+	guard       *sessionGuard
+}
+
+// User is a Fritz!Box user, Last is true for the last user that logged in
+type User struct {
+	Name string `xml:",chardata"`
+	Last bool   `xml:"last,attr"`
+}
+
+// Right is an area of the Fritz!Box configuration
+type Right string
+
+const (
+	RightBoxAdmin Right = "BoxAdmin"
+	RightPhone    Right = "Phone"
+	RightDial     Right = "Dial"
+	RightNAS      Right = "NAS"
+	RightHomeAuto Right = "HomeAuto"
+	RightApp      Right = "App"
+)
+
+// Access is the level of access to a Right
+type Access int
+
+const (
+	AccessNone  Access = 0
+	AccessRead  Access = 1
+	AccessWrite Access = 2
+)
+
+// String returns a readable name of the Access
+func (a Access) String() string {
+	switch a {
+	case AccessNone:
+		return "none"
+	case AccessRead:
+		return "read"
+	case AccessWrite:
+		return "write"
+	default:
+		return "access(" + strconv.Itoa(int(a)) + ")"
+	}
+}
+
+// Rights contains the Access of the logged user for every Right
+// Rights missing in the map are not granted
+type Rights map[Right]Access
+
+// UnmarshalXML decodes the Rights element of login_sid.lua,
+// which is a flat list of alternated <Name> and <Access> elements
+func (r *Rights) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var raw struct {
+		Items []struct {
+			XMLName xml.Name
+			Value   string `xml:",chardata"`
+		} `xml:",any"`
+	}
+	err := d.DecodeElement(&raw, &start)
+	if err != nil {
+		return err
+	}
+
+	rights := Rights{}
+	var name Right
+	for _, item := range raw.Items {
+		switch item.XMLName.Local {
+		case "Name":
+			name = Right(strings.TrimSpace(item.Value))
+		case "Access":
+			access, err := strconv.Atoi(strings.TrimSpace(item.Value))
+			if err != nil {
+				return err
+			}
+			rights[name] = Access(access)
+		}
+	}
+	*r = rights
+	return nil
+}
+
+// CanRead returns true if the user can read the given Right
+func (r Rights) CanRead(right Right) bool {
+	return r[right] >= AccessRead
+}
+
+// CanWrite returns true if the user can modify the given Right
+func (r Rights) CanWrite(right Right) bool {
+	return r[right] >= AccessWrite
+}
+
+// Require returns an InsufficientRightsError if the user doesn't have at least the given Access to the Right
+func (r Rights) Require(right Right, access Access) error {
+	if r[right] >= access {
+		return nil
+	}
+	return &InsufficientRightsError{Right: right, Required: access, Granted: r[right]}
+}
+
+// DefaultLang is the lang used by sessions that didn't choose one
+// German is available on every Fritz!OS, and it's the Locale used by the parsers for unknown langs
+const DefaultLang = "de"
+
+// invalidSID is the SID returned by the Fritz!Box when there's no valid session
+const invalidSID = "0000000000000000"
+
+// SessionStatus is the state of a SID as reported by the Fritz!Box
+type SessionStatus int
+
+const (
+	// SessionUnknown is returned when the Fritz!Box couldn't be asked
+	SessionUnknown SessionStatus = iota
+	// SessionValid means the SID is still accepted
+	SessionValid
+	// SessionExpired means the SID timed out or was logged out
+	SessionExpired
+)
+
+// String returns a readable name of the SessionStatus
+func (s SessionStatus) String() string {
+	switch s {
+	case SessionValid:
+		return "valid"
+	case SessionExpired:
+		return "expired"
+	default:
+		return "unknown"
+	}
+}
+
+// TFAMethod is a way to confirm an action with the second factor
+type TFAMethod string
+
+const (
+	// TFAButton is a press of any button on the Fritz!Box
+	TFAButton TFAMethod = "button"
+	// TFADect is the TFAChallenge DectCode dialed on a phone connected to the Fritz!Box
+	TFADect TFAMethod = "dtmf"
+	// TFATOTP is a code generated by an authenticator app
+	TFATOTP TFAMethod = "googleauth"
+)
+
+// TFAChallenge is an action started by StartAction that waits for the second factor confirmation
+// Methods are the TFAMethod offered by the Fritz!Box, DectCode is the code to dial for TFADect
+type TFAChallenge struct {
+	Methods  []TFAMethod
+	DectCode string
+	session  *SessionInfo
+	page     string
+	values   url.Values
 }
 
 // RequestData contains data about the usual Fritz!Box answer
@@ -45,7 +212,8 @@ type RequestData struct {
 }
 
 // Data contains data about the Fritz!Box
-// WLan needs to be decoded manually, check the type before unmarhsal to WLan or WLanBool
+// WLan is filled when the Fritz!Box sends the Wi-Fi infos, WLanBool is true then, or the value sent instead of them
+// Features missing on the model are sent as false instead of an object, their fields stay nil
 type Data struct {
 	NasLink          string           `json:"naslink"`
 	FritzOS          *FritzOS         `json:"fritzos"`
@@ -76,6 +244,47 @@ type Data struct {
 	ConnectionData   *ConnectionData  `json:"connectionData"`
 }
 
+// UnmarshalJSON decodes Data, dealing with the fields that the Fritz!Box sends with a different type
+func (d *Data) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	// Data has no boolean field, so a boolean is a feature that the model doesn't have
+	for name, value := range fields {
+		if name != "wlan" && isJSONBool(value) {
+			delete(fields, name)
+		}
+	}
+
+	cleaned, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	type plain Data
+	if err := json.Unmarshal(cleaned, (*plain)(d)); err != nil {
+		return err
+	}
+
+	if d.WLanRaw == nil {
+		return nil
+	}
+	if isJSONBool(*d.WLanRaw) {
+		return json.Unmarshal(*d.WLanRaw, &d.WLanBool)
+	}
+	if err := json.Unmarshal(*d.WLanRaw, &d.WLan); err != nil {
+		return err
+	}
+	d.WLanBool = true
+	return nil
+}
+
+// isJSONBool reports whether value is a JSON true or false
+func isJSONBool(value json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(value)
+	return bytes.Equal(trimmed, []byte("true")) || bytes.Equal(trimmed, []byte("false"))
+}
+
 // FritzOS contains infos about the current Fritz!OS version
 type FritzOS struct {
 	Name           string  `json:"Productname"`
@@ -123,50 +332,90 @@ type Internet struct {
 	Link           string    `json:"link"`
 	Provider       string    ``
 	ConnectionTime time.Time ``
+	Lang           string    `json:"-"`
 }
 
 // Sanitize sanitizes the Internet struct by cleaning bad values and generating data from other values
-func (i *Internet) Sanitize() {
-	if i.Led == "globe_online" {
-		i.Online = true
+// Provider and ConnectionTime are read from Txt, which has one line per connection on IPv4/IPv6 boxes,
+// Upload and Download are converted to bit/s from kbit/s, Mbit/s or Gbit/s
+// The text is read with the Locale of Lang, which is set by LoadInfo, a line with a date
+// is a connection time even without the ConnectedSince keyword of the Locale
+// An offline box leaves them empty, an error is returned only for text that can't be understood
+func (i *Internet) Sanitize() error {
+	locale := LocaleFor(i.Lang)
+	i.Online = i.Led == "globe_online"
+	i.Provider = ""
+	i.ConnectionTime = time.Time{}
+	i.Upload = 0
+	i.Download = 0
+
+	dateParser := layoutPattern(locale.DateTime)
+	for _, line := range i.Txt {
+		lower := strings.ToLower(line)
+		// The keyword skips other dates before it, without it any date is the connection time
+		since := strings.Index(lower, strings.ToLower(locale.ConnectedSince))
+		if since < 0 {
+			since = 0
+		}
+		if match := dateParser.FindString(lower[since:]); match != "" {
+			connectionTime, err := time.ParseInLocation(locale.DateTime, match, time.Local)
+			if err != nil {
+				return &ProtocolError{Endpoint: "data.lua", Err: fmt.Errorf("bad connection time %q", match)}
+			}
+			if i.ConnectionTime.IsZero() || connectionTime.Before(i.ConnectionTime) {
+				i.ConnectionTime = connectionTime
+			}
+			continue
+		}
+
+		parts := strings.SplitN(line, ": ", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if strings.EqualFold(strings.TrimSpace(parts[0]), locale.Provider) {
+			i.Provider = strings.TrimSpace(parts[1])
+		} else if i.Provider == "" && !strings.HasPrefix(lower, "ipv") {
+			i.Provider = strings.TrimSpace(parts[1])
+		}
 	}
 
-	i.Provider = strings.SplitN(i.Txt[0], ": ", 2)[1]
+	var err error
+	if i.Upload, err = parseSpeed(i.UploadString, locale); err != nil {
+		return err
+	}
+	if i.Download, err = parseSpeed(i.DownloadString, locale); err != nil {
+		return err
+	}
+	return nil
+}
 
-	timeparser := regexp.MustCompile("([0-9]{1,2}\\.[0-9]{1,2}\\.[0-9]{1,4}, [0-9]{1,2}:[0-9]{1,2})")
-	result := string(timeparser.Find([]byte(i.Txt[1])))
-	readtime, err := time.Parse("02.01.2006, 15:04", result)
-	if err != nil {
-		i.ConnectionTime = readtime
+var speedParser = regexp.MustCompile("(?i)([0-9][0-9., ]*)(kbit|mbit|gbit)/s")
+
+// parseSpeed converts a speed like "100,5 Mbit/s" written in the Locale to bit/s, an empty speed is 0
+func parseSpeed(speed string, locale Locale) (int64, error) {
+	if strings.TrimSpace(speed) == "" {
+		return 0, nil
 	}
 
-	speedparser := regexp.MustCompile("([0-9]{1,5},[0-9]) (Mbit/s|Gbit/s|Tbit/s)")
-	upload := speedparser.FindAllString(i.UploadString, -1)
-	var uploadMultiplier int
-	switch upload[1] {
-	case "Mbit/s":
-		uploadMultiplier = 1000000
-	case "Gbit/s":
-		uploadMultiplier = 1000000000
+	match := speedParser.FindStringSubmatch(speed)
+	if match == nil {
+		return 0, &ProtocolError{Endpoint: "data.lua", Err: fmt.Errorf("bad speed %q", speed)}
+	}
 
-	default:
-		uploadMultiplier = 1000000
+	value, err := strconv.ParseFloat(locale.parseNumber(strings.TrimSpace(match[1])), 64)
+	if err != nil {
+		return 0, &ProtocolError{Endpoint: "data.lua", Err: fmt.Errorf("bad speed %q", speed)}
 	}
-	uploadSpeed, _ := strconv.Atoi(strings.ReplaceAll(upload[0], ",", ""))
-	i.Upload = int64(uploadSpeed / 10 * uploadMultiplier)
-
-	download := speedparser.FindAllString(i.DownloadString, -1)
-	var downloadMultiplier int
-	switch download[1] {
-	case "Mbit/s":
-		downloadMultiplier = 1000000
-	case "Gbit/s":
-		downloadMultiplier = 1000000000
-	default:
-		downloadMultiplier = 1000000
+
+	switch strings.ToLower(match[2]) {
+	case "kbit":
+		value *= 1e3
+	case "mbit":
+		value *= 1e6
+	case "gbit":
+		value *= 1e9
 	}
-	downloadSpeed, _ := strconv.Atoi(strings.ReplaceAll(download[0], ",", ""))
-	i.Download = int64(downloadSpeed / 10 * downloadMultiplier)
+	return int64(math.Round(value)), nil
 }
 
 // DSL contains infos about the connection status
@@ -246,13 +495,101 @@ type WLan struct {
 
 type Logs [][]string
 
-// Filter filters Logs by type
+// LogCategory is the category of a log message, as used by the Fritz!Box
+type LogCategory int
+
+// LogUnknown is the category of the entries without a valid category
+const LogUnknown LogCategory = -1
+
+const (
+	// LogAll is not a category, Logs.Filter and LogQuery use it to keep every message
+	LogAll LogCategory = iota
+	LogSystem
+	LogInternet
+	LogPhone
+	LogWiFi
+	LogUSB
+)
+
+// String returns the name of the LogCategory
+func (c LogCategory) String() string {
+	switch c {
+	case LogUnknown:
+		return "unknown"
+	case LogAll:
+		return "all"
+	case LogSystem:
+		return "system"
+	case LogInternet:
+		return "internet"
+	case LogPhone:
+		return "phone"
+	case LogWiFi:
+		return "wifi"
+	case LogUSB:
+		return "usb"
+	default:
+		return "category " + strconv.Itoa(int(c))
+	}
+}
+
+// LogEntry is a message of the Fritz!Box log
+// Repeat is the number of times the message was logged in a row, Raw is the row as sent by the Fritz!Box
+type LogEntry struct {
+	Time      time.Time
+	Message   string
+	Category  LogCategory
+	MessageID int
+	HelpLink  string
+	Repeat    int
+	Raw       []string
+}
+
+var repeatParser = regexp.MustCompile(" *\\[([0-9]+) [^\\]]*\\]$")
+
+// Entries parses the Logs rows, written in lang, into LogEntry
+// The rows are date, time, message, message ID, category and help link
+// Rows that can't be understood are kept with the fields that could be read, a zero Time if the date is unknown
+func (l Logs) Entries(lang string) LogEntries {
+	locale := LocaleFor(lang)
+	entries := make(LogEntries, 0, len(l))
+	for _, row := range l {
+		entry := LogEntry{Repeat: 1, Raw: row}
+		if len(row) > 1 {
+			entry.Time, _ = time.ParseInLocation(locale.LogTime, row[0]+" "+row[1], time.Local)
+		}
+		if len(row) > 2 {
+			entry.Message = row[2]
+			if match := repeatParser.FindStringSubmatchIndex(row[2]); match != nil {
+				entry.Repeat, _ = strconv.Atoi(row[2][match[2]:match[3]])
+				entry.Message = row[2][:match[0]]
+			}
+		}
+		if len(row) > 3 {
+			entry.MessageID, _ = strconv.Atoi(row[3])
+		}
+		entry.Category = LogUnknown
+		if len(row) > 4 {
+			if category, err := strconv.Atoi(row[4]); err == nil && category > 0 {
+				entry.Category = LogCategory(category)
+			}
+		}
+		if len(row) > 5 {
+			entry.HelpLink = row[5]
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// Filter filters Logs by type, filter is a LogCategory
 // 0 -> All,
 // 1 -> System,
 // 2 -> Internet,
 // 3 -> Phone,
 // 4 -> Wifi,
 // 5 -> USB
+// Use LogEntries.Query for more categories, time ranges and messages
 func (l *Logs) Filter(filter int) Logs {
 	if filter == 0 {
 		return *l
@@ -0,0 +1,194 @@
+/*
+ * GoFritzBox
+ *
+ * Copyright (C) 2016-2021 Dametto Luca <https://damettoluca.com>
+ *
+ * types_test.go is part of GoFritzBox
+ *
+ * You should have received a copy of the GNU Affero General Public License v3.0 along with GoFritzBox.
+ * If not, see <https://github.com/LucaTheHacker/GoFritzBox/blob/main/LICENSE>.
+ */
+
+package GoFritzBox
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRights(t *testing.T) {
+	var session SessionInfo
+	err := xml.Unmarshal([]byte(`<SessionInfo><SID>0123456789abcdef</SID><Rights>`+
+		`<Name>Dial</Name><Access>2</Access><Name>HomeAuto</Name><Access>1</Access>`+
+		`</Rights></SessionInfo>`), &session)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rights := session.Rights
+	if !rights.CanWrite(RightDial) || !rights.CanRead(RightHomeAuto) || rights.CanWrite(RightHomeAuto) || rights.CanRead(RightNAS) {
+		t.Errorf("Rights = %v", rights)
+	}
+	if err := rights.Require(RightDial, AccessWrite); err != nil {
+		t.Errorf("Require(Dial, write) = %v, want nil", err)
+	}
+
+	var rightsErr *InsufficientRightsError
+	if err := rights.Require(RightBoxAdmin, AccessRead); !errors.As(err, &rightsErr) {
+		t.Errorf("Require(BoxAdmin, read) = %v, want an InsufficientRightsError", err)
+	} else if rightsErr.Right != RightBoxAdmin || rightsErr.Required != AccessRead || rightsErr.Granted != AccessNone {
+		t.Errorf("InsufficientRightsError = %+v", rightsErr)
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name     string
+		internet Internet
+		provider string
+		since    time.Time
+		up, down int64
+	}{
+		{
+			name:     "dsl",
+			internet: Internet{Led: "globe_online", Txt: []string{"Anbieter: Telekom", "verbunden seit 01.02.2021, 10:22 Uhr"}, UploadString: "40,0 Mbit/s", DownloadString: "1,0 Gbit/s"},
+			provider: "Telekom", since: time.Date(2021, 2, 1, 10, 22, 0, 0, time.Local), up: 40000000, down: 1000000000,
+		},
+		{
+			name:     "ipv4 and ipv6",
+			internet: Internet{Led: "globe_online", Txt: []string{"IPv4: verbunden seit 3.2.2021, 10:22 Uhr", "IPv6: verbunden seit 02.02.2021, 09:00 Uhr", "Anbieter: Vodafone"}, UploadString: "1.512,5 kbit/s", DownloadString: "100 Mbit/s"},
+			provider: "Vodafone", since: time.Date(2021, 2, 2, 9, 0, 0, 0, time.Local), up: 1512500, down: 100000000,
+		},
+		{
+			name:     "english",
+			internet: Internet{Lang: "en", Led: "globe_online", Txt: []string{"IPv4: connected since 3.2.2021, 10:22", "Provider: Vodafone"}, UploadString: "1,512.5 kbit/s", DownloadString: "100 Mbit/s"},
+			provider: "Vodafone", since: time.Date(2021, 2, 3, 10, 22, 0, 0, time.Local), up: 1512500, down: 100000000,
+		},
+		{
+			name:     "french",
+			internet: Internet{Lang: "fr", Led: "globe_online", Txt: []string{"Fournisseur : Orange", "connecté depuis 01.06.2021, 10:20"}, UploadString: "1 000,5 kbit/s"},
+			provider: "Orange", since: time.Date(2021, 6, 1, 10, 20, 0, 0, time.Local), up: 1000500,
+		},
+		{
+			name:     "italian text without lang",
+			internet: Internet{Led: "globe_online", Txt: []string{"connesso dal 01.06.2021, 10:20"}},
+			since:    time.Date(2021, 6, 1, 10, 20, 0, 0, time.Local),
+		},
+		{
+			name:     "german text with another lang",
+			internet: Internet{Lang: "en", Led: "globe_online", Txt: []string{"IPv4: verbunden seit 01.02.2021, 10:22"}},
+			since:    time.Date(2021, 2, 1, 10, 22, 0, 0, time.Local),
+		},
+		{
+			name:     "offline",
+			internet: Internet{Led: "globe_offline"},
+		},
+	}
+	for _, test := range tests {
+		i := test.internet
+		if err := i.Sanitize(); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if i.Online != (i.Led == "globe_online") || i.Provider != test.provider || !i.ConnectionTime.Equal(test.since) ||
+			i.Upload != test.up || i.Download != test.down {
+			t.Errorf("%s: got %v %q %v %d %d, want %q %v %d %d", test.name, i.Online, i.Provider, i.ConnectionTime, i.Upload, i.Download,
+				test.provider, test.since, test.up, test.down)
+		}
+	}
+}
+
+func TestSanitizeBadSpeed(t *testing.T) {
+	i := Internet{Led: "globe_online", UploadString: "fast"}
+	if err := i.Sanitize(); !errors.Is(err, ErrProtocol) {
+		t.Errorf("err = %v, want ErrProtocol", err)
+	}
+}
+
+func TestParseSpeed(t *testing.T) {
+	tests := []struct {
+		speed string
+		lang  string
+		want  int64
+	}{
+		{"", "de", 0},
+		{"512 kbit/s", "de", 512000},
+		{"100,5 Mbit/s", "de", 100500000},
+		{"1.000,0 kbit/s", "de", 1000000},
+		{"1,2 Gbit/s", "it", 1200000000},
+		{"100 mbit/s", "de", 100000000},
+		{"100.5 Mbit/s", "en", 100500000},
+		{"1 000,5 kbit/s", "fr", 1000500},
+	}
+	for _, test := range tests {
+		got, err := parseSpeed(test.speed, LocaleFor(test.lang))
+		if err != nil || got != test.want {
+			t.Errorf("parseSpeed(%q, %s) = %d, %v, want %d", test.speed, test.lang, got, err, test.want)
+		}
+	}
+}
+
+func TestDataWLan(t *testing.T) {
+	var object, disabled RequestData
+	if err := json.Unmarshal([]byte(`{"data":{"wlan":{"txt":"on","led":"led_green"},"dect":false,"lan":{"txt":"lan"}}}`), &object); err != nil {
+		t.Fatal(err)
+	}
+	if !object.Data.WLanBool || object.Data.WLan.Txt != "on" || object.Data.Dect != nil || object.Data.Lan == nil {
+		t.Errorf("got %+v", object.Data)
+	}
+
+	if err := json.Unmarshal([]byte(`{"data":{"wlan":false}}`), &disabled); err != nil {
+		t.Fatal(err)
+	}
+	if disabled.Data.WLanBool || disabled.Data.WLan != (WLan{}) {
+		t.Errorf("got %+v", disabled.Data)
+	}
+}
+
+func TestLocaleFor(t *testing.T) {
+	if LocaleFor("EN") != Locales["en"] {
+		t.Error("LocaleFor(EN) is not the en Locale")
+	}
+	if LocaleFor("xx") != Locales[DefaultLang] {
+		t.Errorf("LocaleFor of an unknown lang is not the %s Locale", DefaultLang)
+	}
+}
+
+func TestLogsEntries(t *testing.T) {
+	entries := Logs{
+		{"07.03.21", "12:00:01", "DSL resync [3 Meldungen seit 07.03.21 11:00:00]", "23", "2", "/help"},
+		{"8.3.21", "09:30:00", "WLAN-Gerät angemeldet", "", "4"},
+		{"not a date", "", "unknown row"},
+		{"08.03.21"},
+	}.Entries("de")
+	if len(entries) != 4 {
+		t.Fatalf("len(entries) = %d, want 4", len(entries))
+	}
+	first := entries[0]
+	if !first.Time.Equal(time.Date(2021, 3, 7, 12, 0, 1, 0, time.Local)) || first.Message != "DSL resync" ||
+		first.Repeat != 3 || first.MessageID != 23 || first.Category != LogInternet || first.HelpLink != "/help" {
+		t.Errorf("entries[0] = %+v", first)
+	}
+	second := entries[1]
+	if !second.Time.Equal(time.Date(2021, 3, 8, 9, 30, 0, 0, time.Local)) || second.Repeat != 1 || second.Category != LogWiFi {
+		t.Errorf("entries[1] = %+v", second)
+	}
+
+	if third := entries[2]; !third.Time.IsZero() || third.Message != "unknown row" || third.Category != LogUnknown || len(third.Raw) != 3 {
+		t.Errorf("entries[2] = %+v, want the message and the raw row without time", third)
+	}
+	if fourth := entries[3]; !fourth.Time.IsZero() || fourth.Message != "" || fourth.Category != LogUnknown || len(fourth.Raw) != 1 {
+		t.Errorf("entries[3] = %+v, want the raw row only", fourth)
+	}
+}
+
+func TestLogCategoryString(t *testing.T) {
+	for category, want := range map[LogCategory]string{LogAll: "all", LogInternet: "internet", LogUnknown: "unknown", 9: "category 9"} {
+		if got := category.String(); got != want {
+			t.Errorf("LogCategory(%d).String() = %q, want %q", category, got, want)
+		}
+	}
+}